@@ -0,0 +1,304 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+const (
+	apiPathPrefix      = "/api/v4"
+	apiTokenHeader     = "PRIVATE-TOKEN"
+	apiNextPageHeader  = "X-Next-Page"
+	apiPageSize        = 100
+	defaultAPIEndpoint = "https://gitlab.com"
+	defaultAPITimeout  = 30 * time.Second
+)
+
+// gitlabClient is a minimal client of the Gitlab REST API: https://docs.gitlab.com/ee/api/rest/
+type gitlabClient struct {
+	client   *http.Client
+	endpoint string
+	token    string
+}
+
+type apiProject struct {
+	Id                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebUrl            string `json:"web_url"`
+}
+
+// The list of pipelines only contains the basic attributes, the finished time is only part of a single pipeline
+type apiPipeline struct {
+	Id             int     `json:"id"`
+	Sha            string  `json:"sha"`
+	Ref            string  `json:"ref"`
+	Tag            bool    `json:"tag"`
+	Status         string  `json:"status"`
+	Source         string  `json:"source"`
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
+	FinishedAt     string  `json:"finished_at"`
+	Duration       int     `json:"duration"`
+	QueuedDuration float64 `json:"queued_duration"`
+	WebUrl         string  `json:"web_url"`
+	User           User    `json:"user"`
+}
+
+type apiJob struct {
+	Id             int        `json:"id"`
+	Name           string     `json:"name"`
+	Stage          string     `json:"stage"`
+	Status         string     `json:"status"`
+	CreatedAt      string     `json:"created_at"`
+	StartedAt      string     `json:"started_at"`
+	FinishedAt     string     `json:"finished_at"`
+	Duration       float64    `json:"duration"`
+	QueuedDuration float64    `json:"queued_duration"`
+	WebUrl         string     `json:"web_url"`
+	Commit         apiCommit  `json:"commit"`
+	Runner         *apiRunner `json:"runner"`
+}
+
+type apiCommit struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email"`
+	CreatedAt   string `json:"created_at"`
+	WebUrl      string `json:"web_url"`
+}
+
+type apiRunner struct {
+	Id          int    `json:"id"`
+	Description string `json:"description"`
+	Type        string `json:"runner_type"`
+	IsActive    bool   `json:"active"`
+	IsShared    bool   `json:"is_shared"`
+}
+
+// Test report of a pipeline: https://docs.gitlab.com/ee/api/pipelines.html#get-a-pipelines-test-report
+type apiTestReport struct {
+	TotalTime  float64        `json:"total_time"`
+	TotalCount int            `json:"total_count"`
+	TestSuites []apiTestSuite `json:"test_suites"`
+}
+
+// The suite of a job is named after the job, suites of parallel jobs are merged into a single suite
+type apiTestSuite struct {
+	Name         string        `json:"name"`
+	TotalTime    float64       `json:"total_time"`
+	TotalCount   int           `json:"total_count"`
+	SuccessCount int           `json:"success_count"`
+	FailedCount  int           `json:"failed_count"`
+	SkippedCount int           `json:"skipped_count"`
+	ErrorCount   int           `json:"error_count"`
+	SuiteError   string        `json:"suite_error"`
+	TestCases    []apiTestCase `json:"test_cases"`
+	BuildIds     []int         `json:"build_ids"`
+}
+
+type apiTestCase struct {
+	Status        string  `json:"status"`
+	Name          string  `json:"name"`
+	Classname     string  `json:"classname"`
+	File          string  `json:"file"`
+	ExecutionTime float64 `json:"execution_time"`
+	SystemOutput  string  `json:"system_output"`
+	StackTrace    string  `json:"stack_trace"`
+}
+
+func newGitlabClient(ctx context.Context, cfg GitlabAPI, host component.Host, settings component.TelemetrySettings) (*gitlabClient, error) {
+	client, err := cfg.ToClient(ctx, host, settings)
+	if err != nil {
+		return nil, err
+	}
+	return &gitlabClient{
+		client:   client,
+		endpoint: strings.TrimSuffix(cfg.Endpoint, "/"),
+		token:    string(cfg.Token),
+	}, nil
+}
+
+// Projects are identified by their id or their path with namespace
+func (c *gitlabClient) project(ctx context.Context, project string) (apiProject, error) {
+	var p apiProject
+	_, err := c.get(ctx, projectPath(project), nil, &p)
+	return p, err
+}
+
+// Finished pipelines of the project which were updated after the given time, ordered by their update time
+func (c *gitlabClient) pipelines(ctx context.Context, project string, updatedAfter time.Time) ([]apiPipeline, error) {
+	query := url.Values{}
+	query.Set("scope", "finished")
+	query.Set("updated_after", updatedAfter.UTC().Format(time.RFC3339))
+	query.Set("order_by", "updated_at")
+	query.Set("sort", "asc")
+	return getPages[apiPipeline](ctx, c, projectPath(project)+"/pipelines", query)
+}
+
+func (c *gitlabClient) pipeline(ctx context.Context, project string, pipelineId int) (apiPipeline, error) {
+	var p apiPipeline
+	_, err := c.get(ctx, fmt.Sprintf("%s/pipelines/%d", projectPath(project), pipelineId), nil, &p)
+	return p, err
+}
+
+func (c *gitlabClient) jobs(ctx context.Context, project string, pipelineId int) ([]apiJob, error) {
+	return getPages[apiJob](ctx, c, fmt.Sprintf("%s/pipelines/%d/jobs", projectPath(project), pipelineId), url.Values{})
+}
+
+func (c *gitlabClient) testReport(ctx context.Context, project string, pipelineId int) (apiTestReport, error) {
+	var r apiTestReport
+	_, err := c.get(ctx, fmt.Sprintf("%s/pipelines/%d/test_report", projectPath(project), pipelineId), nil, &r)
+	return r, err
+}
+
+func getPages[T any](ctx context.Context, c *gitlabClient, path string, query url.Values) ([]T, error) {
+	var all []T
+	query.Set("per_page", strconv.Itoa(apiPageSize))
+	page := "1"
+	for page != "" {
+		query.Set("page", page)
+		var items []T
+		next, err := c.get(ctx, path, query, &items)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		page = next
+	}
+	return all, nil
+}
+
+// The returned string is the next page of a paginated resource, empty if there are no more pages
+func (c *gitlabClient) get(ctx context.Context, path string, query url.Values, v any) (string, error) {
+	u := c.endpoint + apiPathPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.token != "" {
+		req.Header.Set(apiTokenHeader, c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("request to %s failed with status %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("unable to decode the response of %s: %w", path, err)
+	}
+	return resp.Header.Get(apiNextPageHeader), nil
+}
+
+func projectPath(project string) string {
+	return "/projects/" + url.PathEscape(project)
+}
+
+// The pipeline event is built like the pipeline hook of the pipeline. The times are normalized to the format of the
+// pipeline hook, so that the trace id is the same as for the webhook.
+func newPipelineEventFromAPI(project apiProject, p apiPipeline, jobs []apiJob) (*glPipelineEvent, error) {
+	var err error
+	e := &glPipelineEvent{
+		Kind: pipelineEventKind,
+		Pipeline: Pipeline{
+			Id:             p.Id,
+			Status:         p.Status,
+			Ref:            p.Ref,
+			Tag:            p.Tag,
+			Url:            p.WebUrl,
+			Sha:            p.Sha,
+			Source:         p.Source,
+			Duration:       p.Duration,
+			QueuedDuration: int(p.QueuedDuration),
+		},
+		Project: Project{
+			Name: project.Name,
+			Id:   project.Id,
+			Path: project.PathWithNamespace,
+			Url:  project.WebUrl,
+		},
+		User:   p.User,
+		Commit: Commit{ID: p.Sha},
+	}
+	if e.Pipeline.CreatedAt, err = normalizeAPITime(p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if e.Pipeline.FinishedAt, err = normalizeAPITime(p.FinishedAt); err != nil {
+		return nil, err
+	}
+
+	for _, j := range jobs {
+		job := Job{
+			Id:             j.Id,
+			Name:           j.Name,
+			Status:         j.Status,
+			Stage:          j.Stage,
+			Duration:       j.Duration,
+			QueuedDuration: j.QueuedDuration,
+		}
+		if job.CreatedAt, err = normalizeAPITime(j.CreatedAt); err != nil {
+			return nil, err
+		}
+		if job.StartedAt, err = normalizeAPITime(j.StartedAt); err != nil {
+			return nil, err
+		}
+		if job.FinishedAt, err = normalizeAPITime(j.FinishedAt); err != nil {
+			return nil, err
+		}
+		if j.Runner != nil {
+			job.Runner = Runner{
+				Id:          j.Runner.Id,
+				Description: j.Runner.Description,
+				Type:        j.Runner.Type,
+				IsActive:    j.Runner.IsActive,
+				IsShared:    j.Runner.IsShared,
+			}
+		}
+		e.Jobs = append(e.Jobs, job)
+
+		// Every job carries the commit of the pipeline
+		if j.Commit.Id == p.Sha {
+			e.Commit = Commit{
+				ID:        j.Commit.Id,
+				Message:   j.Commit.Message,
+				Title:     j.Commit.Title,
+				Timestamp: j.Commit.CreatedAt,
+				URL:       j.Commit.WebUrl,
+				Author:    Author{Name: j.Commit.AuthorName, Email: j.Commit.AuthorEmail},
+			}
+		}
+	}
+	return e, nil
+}
+
+// The REST API returns times in RFC3339 with milliseconds, while pipeline hooks contain times in UTC with seconds
+func normalizeAPITime(t string) (string, error) {
+	if t == "" {
+		return "", nil
+	}
+	pt, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q: %w", t, err)
+	}
+	return pt.UTC().Format(gitlabEventTimeFormat), nil
+}
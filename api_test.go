@@ -0,0 +1,235 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+const testAPIToken = "glpat-secret"
+
+func TestGitlabClient(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	client := newTestGitlabClient(t, gitlab)
+
+	project, err := client.project(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Equal(t, gitlab.project, project)
+
+	//The fake Gitlab returns 2 items per page
+	pipelines, err := client.pipelines(context.Background(), "group/project", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, pipelines, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{pipelines[0].Id, pipelines[1].Id, pipelines[2].Id})
+
+	pipelines, err = client.pipelines(context.Background(), "group/project", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, pipelines, 1, "only pipelines updated after the given time are returned")
+
+	pipeline, err := client.pipeline(context.Background(), "group/project", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01T12:40:15.123Z", pipeline.FinishedAt)
+
+	jobs, err := client.jobs(context.Background(), "group/project", 1)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 3)
+
+	report, err := client.testReport(context.Background(), "42", 1)
+	require.NoError(t, err)
+	assert.Equal(t, gitlab.testReports[1], report)
+}
+
+func TestGitlabClientErrors(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	client := newTestGitlabClient(t, gitlab)
+
+	_, err := client.project(context.Background(), "group/unknown")
+	assert.ErrorContains(t, err, "failed with status 404")
+
+	client.token = "invalid"
+	_, err = client.project(context.Background(), "group/project")
+	assert.ErrorContains(t, err, "failed with status 401")
+}
+
+func TestNewPipelineEventFromAPI(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	p, err := newPipelineEventFromAPI(gitlab.project, gitlab.pipelines[0], gitlab.jobs[1])
+	require.NoError(t, err)
+
+	//The event of the API matches the webhook, therefore the trace is the same
+	webhook := newFinishedPipelineEvent()
+	assert.Equal(t, webhook.Pipeline.FinishedAt, p.Pipeline.FinishedAt)
+	assert.Equal(t, webhook.Project, p.Project)
+	assert.Equal(t, "Add feature", p.Commit.Title)
+	require.Len(t, p.Jobs, 3)
+	assert.Equal(t, "", p.Jobs[2].StartedAt)
+	assert.Equal(t, "shared-runner", p.Jobs[0].Runner.Description)
+
+	traceId, rootSpanId, err := p.traceContext()
+	require.NoError(t, err)
+	webhookTraceId, webhookRootSpanId, err := webhook.traceContext()
+	require.NoError(t, err)
+	assert.Equal(t, webhookTraceId, traceId)
+	assert.Equal(t, webhookRootSpanId, rootSpanId)
+}
+
+func TestNormalizeAPITime(t *testing.T) {
+	tests := []struct {
+		name        string
+		time        string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "utc", time: "2024-01-01T12:40:15.123Z", expected: gitlabEndTime},
+		{name: "time zone", time: "2024-01-01T13:40:15.000+01:00", expected: gitlabEndTime},
+		{name: "empty", time: ""},
+		{name: "invalid", time: "2024-01-01 12:40:15 UTC", expectedErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, err := normalizeAPITime(tc.time)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+}
+
+// fakeGitlab serves the Gitlab REST API of a single project
+type fakeGitlab struct {
+	*httptest.Server
+	project     apiProject
+	pipelines   []apiPipeline
+	jobs        map[int][]apiJob
+	testReports map[int]apiTestReport
+	pageSize    int
+}
+
+func newFakeGitlab(t *testing.T) *fakeGitlab {
+	commit := apiCommit{Id: "abc123", Title: "Add feature", Message: "Add feature\n", AuthorName: "user", CreatedAt: "2024-01-01T11:00:00.000Z"}
+	runner := &apiRunner{Id: 1, Description: "shared-runner", Type: "instance_type", IsActive: true, IsShared: true}
+	g := &fakeGitlab{
+		project: apiProject{Id: 42, Name: "project", PathWithNamespace: "group/project", WebUrl: "https://gitlab.com/group/project"},
+		pipelines: []apiPipeline{
+			{Id: 1, Sha: "abc123", Ref: "main", Status: "failed", CreatedAt: "2024-01-01T12:30:15.000Z", UpdatedAt: "2024-01-01T12:40:16.000Z", FinishedAt: "2024-01-01T12:40:15.123Z", WebUrl: "https://gitlab.com/group/project/-/pipelines/1"},
+			{Id: 2, Sha: "def456", Ref: "feature", Status: "success", CreatedAt: "2024-01-01T12:31:00.000Z", UpdatedAt: "2024-01-01T12:50:00.000Z", FinishedAt: "2024-01-01T12:49:59.000Z", WebUrl: "https://gitlab.com/group/project/-/pipelines/2"},
+			{Id: 3, Sha: "ghi789", Ref: "main", Status: "success", CreatedAt: "2024-01-01T13:00:00.000Z", UpdatedAt: "2024-01-01T13:10:00.000Z", FinishedAt: "2024-01-01T13:09:59.000Z", WebUrl: "https://gitlab.com/group/project/-/pipelines/3"},
+		},
+		jobs: map[int][]apiJob{
+			1: {
+				{Id: 11, Name: "test", Stage: "test", Status: "failed", StartedAt: "2024-01-01T12:30:15.000Z", FinishedAt: "2024-01-01T12:40:15.000Z", Commit: commit, Runner: runner},
+				{Id: 12, Name: "build", Stage: "build", Status: "success", StartedAt: "2024-01-01T12:30:15.000Z", FinishedAt: "2024-01-01T12:40:15.000Z", Commit: commit, Runner: runner},
+				{Id: 13, Name: "deploy", Stage: "deploy", Status: "manual", Commit: commit},
+			},
+			2: {{Id: 21, Name: "test", Stage: "test", Status: "success", StartedAt: "2024-01-01T12:31:00.000Z", FinishedAt: "2024-01-01T12:49:59.000Z"}},
+			3: {{Id: 31, Name: "test", Stage: "test", Status: "success", StartedAt: "2024-01-01T13:00:00.000Z", FinishedAt: "2024-01-01T13:09:59.000Z"}},
+		},
+		testReports: map[int]apiTestReport{
+			1: {TotalTime: 4.5, TotalCount: 3, TestSuites: []apiTestSuite{
+				{Name: "test", TotalTime: 4.5, TotalCount: 3, SuccessCount: 2, FailedCount: 1, BuildIds: []int{11}, TestCases: []apiTestCase{
+					{Status: "success", Name: "TestAdd", Classname: "calc", ExecutionTime: 1.5},
+					{Status: "failed", Name: "TestSub", Classname: "calc", ExecutionTime: 2, SystemOutput: "expected 1, got 2"},
+					{Status: "success", Name: "TestMul", Classname: "calc", ExecutionTime: 1},
+				}},
+			}},
+		},
+		pageSize: 2,
+	}
+
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			project := r.PathValue("project")
+			if project != g.project.PathWithNamespace && project != strconv.Itoa(g.project.Id) {
+				http.NotFound(w, r)
+				return
+			}
+			handler(w, r)
+		})
+	}
+	handle("GET /api/v4/projects/{project}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, g.project)
+	})
+	handle("GET /api/v4/projects/{project}/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		updatedAfter, err := time.Parse(time.RFC3339, r.URL.Query().Get("updated_after"))
+		if !assert.NoError(t, err) {
+			http.Error(w, "Invalid updated_after", http.StatusBadRequest)
+			return
+		}
+		var pipelines []apiPipeline
+		for _, p := range g.pipelines {
+			if updatedAt, _ := time.Parse(time.RFC3339, p.UpdatedAt); updatedAt.After(updatedAfter) {
+				pipelines = append(pipelines, p)
+			}
+		}
+		writePage(t, w, r, g.pageSize, pipelines)
+	})
+	handle("GET /api/v4/projects/{project}/pipelines/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range g.pipelines {
+			if strconv.Itoa(p.Id) == r.PathValue("id") {
+				writeJSON(t, w, p)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	handle("GET /api/v4/projects/{project}/pipelines/{id}/jobs", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		writePage(t, w, r, g.pageSize, g.jobs[id])
+	})
+
+	handle("GET /api/v4/projects/{project}/pipelines/{id}/test_report", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		writeJSON(t, w, g.testReports[id])
+	})
+
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(apiTokenHeader) != testAPIToken {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func writePage[T any](t *testing.T, w http.ResponseWriter, r *http.Request, pageSize int, items []T) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if !assert.NoError(t, err) {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	start := min((page-1)*pageSize, len(items))
+	end := min(start+pageSize, len(items))
+	if end < len(items) {
+		w.Header().Set(apiNextPageHeader, strconv.Itoa(page+1))
+	}
+	writeJSON(t, w, items[start:end])
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	assert.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+func newTestGitlabClient(t *testing.T, gitlab *fakeGitlab) *gitlabClient {
+	cfg := createDefaultConfig().(*Config).API
+	cfg.Endpoint = gitlab.URL
+	cfg.Token = testAPIToken
+	client, err := newGitlabClient(context.Background(), cfg, componenttest.NewNopHost(), componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+	return client
+}
@@ -0,0 +1,192 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	backfillStorageName   = "backfill"
+	backfillCheckpointKey = "checkpoint/"
+	defaultBackfillWindow = 24 * time.Hour
+)
+
+// backfiller exports the pipelines which finished while the collector wasn't running. The pipelines are pulled from the
+// Gitlab REST API once after the start of the receiver. The update time of the last backfilled pipeline of every project is
+// persisted as checkpoint, so that only newer pipelines are backfilled after the next start.
+type backfiller struct {
+	cfg      Backfill
+	projects []string
+	logger   *zap.Logger
+	api      *gitlabClient
+	client   storage.Client
+	export   func(ctx context.Context, p *glPipelineEvent) error
+	now      func() time.Time
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// Several pipelines can be updated within the same second, the ids of the pipelines which were backfilled at the time of
+// the checkpoint are persisted with it so that the remaining ones of the same time are still backfilled.
+type backfillCheckpoint struct {
+	UpdatedAt   time.Time `json:"updated_at"`
+	PipelineIds []int     `json:"pipeline_ids"`
+}
+
+func (c backfillCheckpoint) contains(updatedAt time.Time, pipelineId int) bool {
+	if updatedAt.Equal(c.UpdatedAt) {
+		return slices.Contains(c.PipelineIds, pipelineId)
+	}
+	return updatedAt.Before(c.UpdatedAt)
+}
+
+func (c backfillCheckpoint) advance(updatedAt time.Time, pipelineId int) backfillCheckpoint {
+	if updatedAt.Equal(c.UpdatedAt) {
+		return backfillCheckpoint{UpdatedAt: c.UpdatedAt, PipelineIds: append(slices.Clone(c.PipelineIds), pipelineId)}
+	}
+	return backfillCheckpoint{UpdatedAt: updatedAt, PipelineIds: []int{pipelineId}}
+}
+
+func newBackfiller(cfg Backfill, projects []string, api *gitlabClient, export func(ctx context.Context, p *glPipelineEvent) error, logger *zap.Logger) *backfiller {
+	return &backfiller{
+		cfg:      cfg,
+		projects: projects,
+		logger:   logger,
+		api:      api,
+		client:   storage.NewNopClient(),
+		export:   export,
+		now:      time.Now,
+	}
+}
+
+func (b *backfiller) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.run(ctx)
+	}()
+}
+
+func (b *backfiller) shutdown(ctx context.Context) error {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.wg.Wait()
+	return b.client.Close(ctx)
+}
+
+// A failing project doesn't stop the backfill of the other projects
+func (b *backfiller) run(ctx context.Context) {
+	for _, project := range b.projects {
+		backfilled, err := b.backfillProject(ctx, project)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			b.logger.Error("Unable to backfill the pipelines of the project", zap.String("project", project), zap.Int("pipelines", backfilled), zap.Error(err))
+			continue
+		}
+		b.logger.Info("Backfilled the pipelines of the project", zap.String("project", project), zap.Int("pipelines", backfilled))
+	}
+}
+
+// Pipelines are backfilled in the order of their update time, the checkpoint is only moved forward once a pipeline is backfilled
+func (b *backfiller) backfillProject(ctx context.Context, project string) (int, error) {
+	since := b.now().Add(-b.cfg.Window)
+	checkpoint, err := b.loadCheckpoint(ctx, project)
+	if err != nil {
+		return 0, err
+	}
+	// The pipelines updated within the second of the checkpoint are requested again, the ones which were already
+	// backfilled are skipped by their id
+	if checkpoint.UpdatedAt.After(since) {
+		since = checkpoint.UpdatedAt.Add(-time.Second)
+	}
+
+	p, err := b.api.project(ctx, project)
+	if err != nil {
+		return 0, err
+	}
+	pipelines, err := b.api.pipelines(ctx, project, since)
+	if err != nil {
+		return 0, err
+	}
+
+	backfilled := 0
+	for _, pipeline := range pipelines {
+		updatedAt, err := time.Parse(time.RFC3339, pipeline.UpdatedAt)
+		if err != nil {
+			return backfilled, fmt.Errorf("invalid update time of pipeline %d: %w", pipeline.Id, err)
+		}
+		// The pipelines of the checkpoint were already backfilled
+		if checkpoint.contains(updatedAt, pipeline.Id) {
+			continue
+		}
+
+		err = b.backfillPipeline(ctx, project, p, pipeline.Id)
+		if err != nil {
+			return backfilled, fmt.Errorf("unable to backfill pipeline %d: %w", pipeline.Id, err)
+		}
+		backfilled++
+
+		checkpoint = checkpoint.advance(updatedAt, pipeline.Id)
+		if err := b.persistCheckpoint(ctx, project, checkpoint); err != nil {
+			b.logger.Warn("Unable to persist the backfill checkpoint", zap.String("project", project), zap.Error(err))
+		}
+	}
+	return backfilled, nil
+}
+
+func (b *backfiller) backfillPipeline(ctx context.Context, project string, p apiProject, pipelineId int) error {
+	pipeline, err := b.api.pipeline(ctx, project, pipelineId)
+	if err != nil {
+		return err
+	}
+	jobs, err := b.api.jobs(ctx, project, pipelineId)
+	if err != nil {
+		return err
+	}
+	e, err := newPipelineEventFromAPI(p, pipeline, jobs)
+	if err != nil {
+		return err
+	}
+
+	err = b.export(ctx, e)
+	if errors.Is(err, errNotExported) {
+		return nil
+	}
+	return err
+}
+
+func (b *backfiller) loadCheckpoint(ctx context.Context, project string) (backfillCheckpoint, error) {
+	var checkpoint backfillCheckpoint
+	data, err := b.client.Get(ctx, backfillCheckpointKey+project)
+	if err != nil {
+		return checkpoint, fmt.Errorf("unable to load the backfill checkpoint: %w", err)
+	}
+	if data == nil {
+		return checkpoint, nil
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("unable to decode the backfill checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (b *backfiller) persistCheckpoint(ctx context.Context, project string, checkpoint backfillCheckpoint) error {
+	data, err := json.Marshal(backfillCheckpoint{UpdatedAt: checkpoint.UpdatedAt.UTC(), PipelineIds: checkpoint.PipelineIds})
+	if err != nil {
+		return err
+	}
+	return b.client.Set(ctx, backfillCheckpointKey+project, data)
+}
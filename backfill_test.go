@@ -0,0 +1,149 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.uber.org/zap"
+)
+
+func TestBackfiller(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, createDefaultConfig().(*Config), receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	client := newMemoryStorageClient()
+	b := newTestBackfiller(t, gitlab, glRcvr.handlePipelineTraces)
+	b.client = client
+
+	backfilled, err := b.backfillProject(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Equal(t, 3, backfilled)
+	require.Len(t, sink.AllTraces(), 3)
+
+	//The backfilled trace is the same as the trace of the webhook
+	webhook, err := newFinishedPipelineEvent().newTrace()
+	require.NoError(t, err)
+	spans := collectSpans(sink.AllTraces()[0])
+	assert.Equal(t, collectSpans(*webhook)["Job: test - 11 - Stage: test"].SpanID(), spans["Job: test - 11 - Stage: test"].SpanID())
+	assert.Equal(t, collectSpans(*webhook)["Job: test - 11 - Stage: test"].TraceID(), spans["Job: test - 11 - Stage: test"].TraceID())
+	assert.JSONEq(t, `{"updated_at":"2024-01-01T13:10:00Z","pipeline_ids":[3]}`, string(client.data[backfillCheckpointKey+"group/project"]))
+
+	//A restarted backfill continues after the checkpoint
+	gitlab.pipelines = append(gitlab.pipelines, apiPipeline{Id: 4, Sha: "jkl012", Ref: "main", Status: "success", CreatedAt: "2024-01-01T13:20:00.000Z", UpdatedAt: "2024-01-01T13:30:00.000Z", FinishedAt: "2024-01-01T13:29:59.000Z"})
+	restarted := newTestBackfiller(t, gitlab, glRcvr.handlePipelineTraces)
+	restarted.client = client
+	backfilled, err = restarted.backfillProject(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Equal(t, 1, backfilled)
+	assert.Len(t, sink.AllTraces(), 4)
+}
+
+func TestBackfillerProjects(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Projects.Exclude = []string{"group/project"}
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	//The pipelines of excluded projects aren't backfilled, even if they are part of the api projects
+	b := newTestBackfiller(t, gitlab, glRcvr.handlePipelineTraces)
+	_, err := b.backfillProject(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Empty(t, sink.AllTraces())
+}
+
+func TestBackfillerWindow(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, createDefaultConfig().(*Config), receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	//Only pipelines which were updated within the window are exported
+	b := newTestBackfiller(t, gitlab, glRcvr.handlePipelineTraces)
+	b.cfg.Window = 10 * time.Minute
+	backfilled, err := b.backfillProject(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Equal(t, 1, backfilled)
+}
+
+func TestBackfillerExportFailure(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	client := newMemoryStorageClient()
+	failing := func(_ context.Context, p *glPipelineEvent) error {
+		if p.Pipeline.Id == 2 {
+			return errors.New("pipeline is backed up")
+		}
+		return nil
+	}
+	b := newTestBackfiller(t, gitlab, failing)
+	b.client = client
+
+	//The checkpoint isn't moved beyond a pipeline which wasn't exported
+	backfilled, err := b.backfillProject(context.Background(), "group/project")
+	assert.ErrorContains(t, err, "unable to backfill pipeline 2")
+	assert.Equal(t, 1, backfilled)
+	assert.JSONEq(t, `{"updated_at":"2024-01-01T12:40:16Z","pipeline_ids":[1]}`, string(client.data[backfillCheckpointKey+"group/project"]))
+}
+
+func TestBackfillerCheckpointSameUpdateTime(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	gitlab.pipelines = append(gitlab.pipelines, apiPipeline{Id: 4, Sha: "jkl012", Ref: "main", Status: "success", CreatedAt: "2024-01-01T13:00:00.000Z", UpdatedAt: "2024-01-01T13:10:00.000Z", FinishedAt: "2024-01-01T13:09:59.000Z"})
+	client := newMemoryStorageClient()
+	var exported []int
+	failing := func(_ context.Context, p *glPipelineEvent) error {
+		if p.Pipeline.Id == 4 {
+			return errors.New("pipeline is backed up")
+		}
+		exported = append(exported, p.Pipeline.Id)
+		return nil
+	}
+	b := newTestBackfiller(t, gitlab, failing)
+	b.client = client
+	_, err := b.backfillProject(context.Background(), "group/project")
+	assert.ErrorContains(t, err, "unable to backfill pipeline 4")
+	assert.JSONEq(t, `{"updated_at":"2024-01-01T13:10:00Z","pipeline_ids":[3]}`, string(client.data[backfillCheckpointKey+"group/project"]))
+
+	//The pipeline updated within the same second as the checkpoint is still backfilled, the one of the checkpoint isn't
+	restarted := newTestBackfiller(t, gitlab, func(_ context.Context, p *glPipelineEvent) error {
+		exported = append(exported, p.Pipeline.Id)
+		return nil
+	})
+	restarted.client = client
+	backfilled, err := restarted.backfillProject(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Equal(t, 1, backfilled)
+	assert.Equal(t, []int{1, 2, 3, 4}, exported)
+	assert.JSONEq(t, `{"updated_at":"2024-01-01T13:10:00Z","pipeline_ids":[3,4]}`, string(client.data[backfillCheckpointKey+"group/project"]))
+}
+
+func TestBackfillerFilteredRefs(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.Refs = []string{"main"}
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	b := newTestBackfiller(t, gitlab, glRcvr.handlePipelineTraces)
+	backfilled, err := b.backfillProject(context.Background(), "group/project")
+	require.NoError(t, err)
+	assert.Equal(t, 3, backfilled)
+	assert.Len(t, sink.AllTraces(), 2, "pipelines of other refs are not exported")
+}
+
+func newTestBackfiller(t *testing.T, gitlab *fakeGitlab, export func(ctx context.Context, p *glPipelineEvent) error) *backfiller {
+	b := newBackfiller(Backfill{Enabled: true, Window: defaultBackfillWindow}, []string{"group/project"}, newTestGitlabClient(t, gitlab), export, zap.NewNop())
+	b.now = func() time.Time {
+		return time.Date(2024, 1, 1, 13, 15, 0, 0, time.UTC)
+	}
+	return b
+}
@@ -0,0 +1,105 @@
+package gitlabreceiver
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ttlCache is a size bounded cache whose entries expire after the configured ttl. If the cache is full, the least recently written entry is evicted.
+type ttlCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxSize int
+	ttl     time.Duration
+	entries map[K]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLCache[K comparable, V any](maxSize int, ttl time.Duration) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *ttlCache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.getLocked(key)
+}
+
+func (c *ttlCache[K, V]) set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(key, value)
+}
+
+// update atomically replaces the value of key with the result of fn, unless fn returns false. Values should be treated as immutable,
+// fn must return a new value instead of modifying the given one.
+func (c *ttlCache[K, V]) update(key K, fn func(value V, found bool) (V, bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, found := c.getLocked(key)
+	value, ok := fn(value, found)
+	if ok {
+		c.setLocked(key, value)
+	}
+}
+
+func (c *ttlCache[K, V]) delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+func (c *ttlCache[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *ttlCache[K, V]) getLocked(key K) (V, bool) {
+	var value V
+	e, ok := c.entries[key]
+	if !ok {
+		return value, false
+	}
+	entry := e.Value.(*cacheEntry[K, V])
+	if c.now().After(entry.expiresAt) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return value, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[K, V]) setLocked(key K, value V) {
+	expiresAt := c.now().Add(c.ttl)
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cacheEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToBack(e)
+		return
+	}
+
+	for c.order.Len() > 0 && c.order.Len() >= c.maxSize {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[K, V]).key)
+	}
+	c.entries[key] = c.order.PushBack(&cacheEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+}
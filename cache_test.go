@@ -0,0 +1,49 @@
+package gitlabreceiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLCache(t *testing.T) {
+	now := time.Unix(1704112215, 0)
+	c := newTTLCache[int, string](2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.set(1, "a")
+	c.set(2, "b")
+	v, ok := c.get(1)
+	assert.True(t, ok)
+	assert.Equal(t, "a", v)
+
+	//Exceeding the max size evicts the least recently written entry
+	c.set(3, "c")
+	_, ok = c.get(1)
+	assert.False(t, ok, "entry should be evicted")
+	assert.Equal(t, 2, c.len())
+
+	c.update(2, func(v string, found bool) (string, bool) {
+		assert.True(t, found)
+		return v + "b", true
+	})
+	v, _ = c.get(2)
+	assert.Equal(t, "bb", v)
+
+	c.update(4, func(v string, found bool) (string, bool) {
+		assert.False(t, found)
+		return "d", false
+	})
+	_, ok = c.get(4)
+	assert.False(t, ok, "update should not store the value")
+
+	c.delete(2)
+	_, ok = c.get(2)
+	assert.False(t, ok, "entry should be deleted")
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.get(3)
+	assert.False(t, ok, "entry should be expired")
+	assert.Equal(t, 0, c.len())
+}
@@ -0,0 +1,36 @@
+// Command traceparent prints the W3C traceparent of the span of the current Gitlab CI job. By default the predefined
+// CI/CD variables CI_COMMIT_SHA, CI_PIPELINE_ID and CI_JOB_ID are used:
+//
+//	export TRACEPARENT=$(traceparent)
+//
+// The traceparent only matches the exported trace if the gitlabreceiver is configured with traces.stable_trace_id.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nw0rn/gitlabreceiver"
+)
+
+func main() {
+	sha := flag.String("sha", os.Getenv("CI_COMMIT_SHA"), "commit sha of the pipeline")
+	pipelineId := flag.Int("pipeline-id", envInt("CI_PIPELINE_ID"), "id of the pipeline")
+	jobId := flag.Int("job-id", envInt("CI_JOB_ID"), "id of the job")
+	flag.Parse()
+
+	traceParent, err := gitlabreceiver.TraceParent(*sha, *pipelineId, *jobId)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "traceparent: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(traceParent)
+}
+
+// Invalid values are reported by TraceParent
+func envInt(key string) int {
+	v, _ := strconv.Atoi(os.Getenv(key))
+	return v
+}
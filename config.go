@@ -5,45 +5,283 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"slices"
+	"time"
 
 	"go.opentelemetry.io/collector/confmap"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configretry"
 )
 
 const (
-	defaultTracesUrlPath = "/v0.1/traces"
-	gitlabPathPrefix     = "path-"
+	defaultTracesUrlPath  = "/v0.1/traces"
+	defaultMetricsUrlPath = "/v0.1/metrics"
+	defaultLogsUrlPath    = "/v0.1/logs"
+	gitlabPathPrefix      = "path-"
+	defaultQueueSize      = 1000
+	// Gitlab doesn't send webhooks with a payload larger than 25 MB
+	defaultMaxRequestBodySize = 25 * 1024 * 1024
 )
 
 var typeStr = component.MustNewType("gitlab")
 
 type Traces struct {
-	UrlPath string   `mapstructure:"url_path,omitempty"`
-	Refs    []string `mapstructure:"refs,omitempty"`
+	UrlPath string `mapstructure:"url_path,omitempty"`
+	// Refs and ExcludeRefs are names, globs (e.g. release/*) or regular expressions enclosed in slashes of the refs whose
+	// pipelines are exported. Patterns starting with refs/heads/ or refs/tags/ only match branches or tags.
+	Refs        []string `mapstructure:"refs,omitempty"`
+	ExcludeRefs []string `mapstructure:"exclude_refs,omitempty"`
+	// StageSpans groups the job spans by stage. By default all job spans are direct children of the pipeline span.
+	StageSpans bool `mapstructure:"stage_spans,omitempty"`
+	// JobPhases starts the job spans with the creation of the job and splits them into a queued and an execution span.
+	// By default the job spans start when the job was picked up by a runner.
+	JobPhases bool `mapstructure:"job_phases,omitempty"`
+	// StableTraceId leaves the finished time of the pipeline out of the trace id, so that CI jobs can calculate the
+	// trace context of their job span (see TraceParent). Retried pipelines are exported into the same trace.
+	StableTraceId bool        `mapstructure:"stable_trace_id,omitempty"`
+	Queue         Queue       `mapstructure:"queue"`
+	Backfill      Backfill    `mapstructure:"backfill"`
+	Reconcile     Reconcile   `mapstructure:"reconcile"`
+	TestReports   TestReports `mapstructure:"test_reports"`
+}
+
+// TestReports adds the test suites and test cases of the test report of finished pipelines to their trace. The report is
+// fetched from the Gitlab REST API after the delay, because Gitlab parses the reports of the jobs asynchronously.
+type TestReports struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Delay   time.Duration `mapstructure:"delay"`
+}
+
+// Backfill exports the pipelines of the configured projects which finished within the window before the start of the
+// receiver, the pipelines are pulled from the Gitlab REST API. The update time of the last exported pipeline of every
+// project is persisted in the configured storage extension, so that pipelines are only exported once across restarts.
+type Backfill struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Window    time.Duration `mapstructure:"window"`
+	StorageID *component.ID `mapstructure:"storage,omitempty"`
+}
+
+// Reconcile periodically lists the finished pipelines of the configured projects through the Gitlab REST API and exports
+// the pipelines whose webhook was missed. Pipelines which were updated within the delay are left to their webhook.
+type Reconcile struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	Window   time.Duration `mapstructure:"window"`
+	Delay    time.Duration `mapstructure:"delay"`
+}
+
+// Queue persists the traces in the configured storage extension before they are exported. Webhooks are acknowledged once
+// the traces are persisted, the export is retried in the background and continues after a restart of the collector.
+type Queue struct {
+	Enabled        bool                      `mapstructure:"enabled"`
+	StorageID      *component.ID             `mapstructure:"storage,omitempty"`
+	QueueSize      int                       `mapstructure:"queue_size"`
+	RetryOnFailure configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+}
+
+type Metrics struct {
+	UrlPath string `mapstructure:"url_path,omitempty"`
+	// Metrics are enabled by default, single metrics can be disabled by their name
+	Enabled map[string]bool `mapstructure:"enabled,omitempty"`
+	// Refs and ExcludeRefs filter the pipelines like for traces, but are configured separately because metrics usually
+	// cover more refs than traces. By default metrics are created for all refs.
+	Refs        []string `mapstructure:"refs,omitempty"`
+	ExcludeRefs []string `mapstructure:"exclude_refs,omitempty"`
+	Dora        Dora     `mapstructure:"dora"`
+}
+
+// DORA metrics are calculated out of the deployment jobs of finished pipelines. The counters are cumulative, therefore
+// the state is persisted in the configured storage extension to survive restarts. Without storage the state is kept in memory only.
+type Dora struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Environments which are considered as production. By default all environments with the production deployment tier are considered.
+	Environments []string      `mapstructure:"environments,omitempty"`
+	StorageID    *component.ID `mapstructure:"storage,omitempty"`
+}
+
+func (m Metrics) isEnabled(name string) bool {
+	enabled, ok := m.Enabled[name]
+	return !ok || enabled
+}
+
+type Logs struct {
+	UrlPath string `mapstructure:"url_path,omitempty"`
+}
+
+// GitlabAPI is the connection to the Gitlab REST API. The endpoint is the url of the Gitlab instance, the token needs
+// the read_api scope for the configured projects.
+type GitlabAPI struct {
+	confighttp.ClientConfig `mapstructure:",squash"`
+	Token                   configopaque.String `mapstructure:"token,omitempty"`
+	// Projects are identified by their id or their path with namespace (e.g. group/project)
+	Projects []string `mapstructure:"projects,omitempty"`
+}
+
+// Projects filters the events by their project before any telemetry is created out of them. Patterns are ids of projects,
+// paths of projects or groups (e.g. group/subgroup), globs (e.g. group/team-*) or regular expressions enclosed in slashes.
+type Projects struct {
+	Include []string `mapstructure:"include,omitempty"`
+	Exclude []string `mapstructure:"exclude,omitempty"`
+}
+
+// Dedup remembers the handled webhooks for the ttl, redelivered webhooks are answered without exporting them again
+type Dedup struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	TTL        time.Duration `mapstructure:"ttl"`
+	MaxEntries int           `mapstructure:"max_entries"`
 }
 
 type Config struct {
 	confighttp.ServerConfig `mapstructure:",squash"`
-	Traces                  Traces `mapstructure:"traces"`
+	// SecretToken is compared against the X-Gitlab-Token header of every webhook. SecretTokens allows
+	// additional tokens to be accepted at the same time, e.g. while rotating the secret in Gitlab.
+	SecretToken  configopaque.String   `mapstructure:"secret_token,omitempty"`
+	SecretTokens []configopaque.String `mapstructure:"secret_tokens,omitempty"`
+	// SigningKey is the signing token of the Gitlab webhook (whsec_...). If set, the webhook-signature header of every
+	// webhook is verified and webhooks older than SignatureTolerance are rejected.
+	SigningKey         configopaque.String `mapstructure:"signing_key,omitempty"`
+	SignatureTolerance time.Duration       `mapstructure:"signature_tolerance,omitempty"`
+	Dedup              Dedup               `mapstructure:"dedup"`
+	Projects           Projects            `mapstructure:"projects"`
+	API                GitlabAPI           `mapstructure:"api"`
+	Traces             Traces              `mapstructure:"traces"`
+	Metrics            Metrics             `mapstructure:"metrics"`
+	Logs               Logs                `mapstructure:"logs"`
 }
 
 func (cfg *Config) Validate() error {
-	if len(cfg.Traces.Refs) > 50 {
-		return errors.New("configured amount of refs is exceeding the limit of 50")
+	if _, err := newRefFilter(cfg.Traces.Refs, cfg.Traces.ExcludeRefs); err != nil {
+		return fmt.Errorf("traces: %w", err)
+	}
+	if _, err := newRefFilter(cfg.Metrics.Refs, cfg.Metrics.ExcludeRefs); err != nil {
+		return fmt.Errorf("metrics: %w", err)
+	}
+	if _, err := newProjectFilter(cfg.Projects.Include, cfg.Projects.Exclude); err != nil {
+		return fmt.Errorf("projects: %w", err)
+	}
+	for _, t := range cfg.SecretTokens {
+		if t == "" {
+			return errors.New("secret_tokens must not contain empty tokens")
+		}
+	}
+	for name := range cfg.Metrics.Enabled {
+		if !slices.Contains(supportedMetrics, name) {
+			return fmt.Errorf("unknown metric %q", name)
+		}
+	}
+	if cfg.Dedup.Enabled {
+		if cfg.Dedup.TTL <= 0 {
+			return errors.New("dedup.ttl must be greater than 0")
+		}
+		if cfg.Dedup.MaxEntries <= 0 {
+			return errors.New("dedup.max_entries must be greater than 0")
+		}
+	}
+	if cfg.usesAPI() && cfg.API.Endpoint == "" {
+		return errors.New("api.endpoint must be configured if the backfill, the reconciliation or the test reports are enabled")
+	}
+	if (cfg.Traces.Backfill.Enabled || cfg.Traces.Reconcile.Enabled) && len(cfg.API.Projects) == 0 {
+		return errors.New("api.projects must be configured if the backfill or the reconciliation is enabled")
+	}
+	if cfg.Traces.TestReports.Enabled && cfg.Traces.TestReports.Delay < 0 {
+		return errors.New("traces.test_reports.delay must not be negative")
+	}
+	if cfg.Traces.Backfill.Enabled && cfg.Traces.Backfill.Window <= 0 {
+		return errors.New("traces.backfill.window must be greater than 0")
+	}
+	if cfg.Traces.Reconcile.Enabled {
+		if cfg.Traces.Reconcile.Interval <= 0 {
+			return errors.New("traces.reconcile.interval must be greater than 0")
+		}
+		if cfg.Traces.Reconcile.Delay < 0 {
+			return errors.New("traces.reconcile.delay must not be negative")
+		}
+		if cfg.Traces.Reconcile.Window <= cfg.Traces.Reconcile.Delay {
+			return errors.New("traces.reconcile.window must be greater than the delay")
+		}
+	}
+	if cfg.Traces.Queue.Enabled {
+		if cfg.Traces.Queue.StorageID == nil {
+			return errors.New("traces.queue.storage must be configured if the queue is enabled")
+		}
+		if cfg.Traces.Queue.QueueSize <= 0 {
+			return errors.New("traces.queue.queue_size must be greater than 0")
+		}
+		if err := cfg.Traces.Queue.RetryOnFailure.Validate(); err != nil {
+			return fmt.Errorf("traces.queue.retry_on_failure: %w", err)
+		}
+	}
+	if cfg.SigningKey != "" {
+		if _, err := decodeSigningKey(string(cfg.SigningKey)); err != nil {
+			return err
+		}
+		if cfg.SignatureTolerance <= 0 {
+			return errors.New("signature_tolerance must be greater than 0")
+		}
 	}
 	return nil
 }
 
+// The Gitlab REST API is only used by the backfill, the reconciliation and the test reports
+func (cfg *Config) usesAPI() bool {
+	return cfg.Traces.Backfill.Enabled || cfg.Traces.Reconcile.Enabled || cfg.Traces.TestReports.Enabled
+}
+
+// All tokens which are accepted in the X-Gitlab-Token header. No tokens means that the header isn't validated.
+func (cfg *Config) secretTokens() []configopaque.String {
+	tokens := make([]configopaque.String, 0, len(cfg.SecretTokens)+1)
+	if cfg.SecretToken != "" {
+		tokens = append(tokens, cfg.SecretToken)
+	}
+	return append(tokens, cfg.SecretTokens...)
+}
+
 func createDefaultConfig() component.Config {
+	apiClient := confighttp.NewDefaultClientConfig()
+	apiClient.Endpoint = defaultAPIEndpoint
+	apiClient.Timeout = defaultAPITimeout
+
 	return &Config{
 		ServerConfig: confighttp.ServerConfig{
-			Endpoint: "localhost:9286",
+			Endpoint:           "localhost:9286",
+			MaxRequestBodySize: defaultMaxRequestBodySize,
+		},
+		SignatureTolerance: defaultSignatureTimeout,
+		Dedup: Dedup{
+			TTL:        defaultDedupTTL,
+			MaxEntries: defaultDedupEntries,
+		},
+		API: GitlabAPI{
+			ClientConfig: apiClient,
 		},
 		Traces: Traces{
 			UrlPath: defaultTracesUrlPath,
 			Refs:    []string{},
+			Queue: Queue{
+				QueueSize:      defaultQueueSize,
+				RetryOnFailure: configretry.NewDefaultBackOffConfig(),
+			},
+			Backfill: Backfill{
+				Window: defaultBackfillWindow,
+			},
+			Reconcile: Reconcile{
+				Interval: defaultReconcileInterval,
+				Window:   defaultReconcileWindow,
+				Delay:    defaultReconcileDelay,
+			},
+			TestReports: TestReports{
+				Delay: defaultTestReportsDelay,
+			},
+		},
+		Metrics: Metrics{
+			UrlPath: defaultMetricsUrlPath,
+			Enabled: map[string]bool{},
+		},
+		Logs: Logs{
+			UrlPath: defaultLogsUrlPath,
 		},
 	}
 }
@@ -57,6 +295,14 @@ func (cfg *Config) Unmarshal(conf *confmap.Conf) error {
 	if err != nil {
 		return err
 	}
+	cfg.Metrics.UrlPath, err = sanitizeURLPath(cfg.Metrics.UrlPath)
+	if err != nil {
+		return err
+	}
+	cfg.Logs.UrlPath, err = sanitizeURLPath(cfg.Logs.UrlPath)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -2,9 +2,14 @@ package gitlabreceiver
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configretry"
 )
 
 func TestCreateDefaultConfig(t *testing.T) {
@@ -44,3 +49,248 @@ func TestSanitizeUrlPath(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigValidate(t *testing.T) {
+	storageID := component.MustNewID("file_storage")
+	tests := []struct {
+		name        string
+		cfg         *Config
+		expectedErr bool
+	}{
+		{
+			name: "default config",
+			cfg:  createDefaultConfig().(*Config),
+		},
+		{
+			name: "multiple secret tokens",
+			cfg: &Config{
+				SecretToken:  "secret",
+				SecretTokens: []configopaque.String{"old-secret", "new-secret"},
+			},
+		},
+		{
+			name: "valid signing key",
+			cfg: &Config{
+				SigningKey:         testSigningKey,
+				SignatureTolerance: defaultSignatureTimeout,
+			},
+		},
+		{
+			name: "invalid signing key",
+			cfg: &Config{
+				SigningKey:         "whsec_not-base64!",
+				SignatureTolerance: defaultSignatureTimeout,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "signing key without tolerance",
+			cfg: &Config{
+				SigningKey: testSigningKey,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "disabled metric",
+			cfg: &Config{
+				Metrics: Metrics{Enabled: map[string]bool{metricRunnerUsage: false}},
+			},
+		},
+		{
+			name: "unknown metric",
+			cfg: &Config{
+				Metrics: Metrics{Enabled: map[string]bool{"cicd.unknown": false}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "ref patterns",
+			cfg: &Config{
+				Traces: Traces{Refs: []string{"main", "release/*", "refs/tags/*"}, ExcludeRefs: []string{`/^renovate\//`}},
+			},
+		},
+		{
+			name: "invalid ref pattern",
+			cfg: &Config{
+				Traces: Traces{Refs: []string{"release/["}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid metrics ref pattern",
+			cfg: &Config{
+				Metrics: Metrics{Refs: []string{"release/["}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid excluded ref regexp",
+			cfg: &Config{
+				Traces: Traces{ExcludeRefs: []string{"/(/"}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "project filter",
+			cfg: &Config{
+				Projects: Projects{Include: []string{"group", "42", "/^other\\/.*/"}, Exclude: []string{"group/team-*"}},
+			},
+		},
+		{
+			name: "invalid project pattern",
+			cfg: &Config{
+				Projects: Projects{Exclude: []string{"/(/"}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "dedup",
+			cfg: &Config{
+				Dedup: Dedup{Enabled: true, TTL: time.Hour, MaxEntries: 100},
+			},
+		},
+		{
+			name: "dedup without ttl",
+			cfg: &Config{
+				Dedup: Dedup{Enabled: true, MaxEntries: 100},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "dedup without max entries",
+			cfg: &Config{
+				Dedup: Dedup{Enabled: true, TTL: time.Hour},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "backfill",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}, Projects: []string{"group/project"}},
+				Traces: Traces{Backfill: Backfill{Enabled: true, Window: time.Hour}},
+			},
+		},
+		{
+			name: "backfill without projects",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}},
+				Traces: Traces{Backfill: Backfill{Enabled: true, Window: time.Hour}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "backfill without endpoint",
+			cfg: &Config{
+				API:    GitlabAPI{Projects: []string{"group/project"}},
+				Traces: Traces{Backfill: Backfill{Enabled: true, Window: time.Hour}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "backfill without window",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}, Projects: []string{"group/project"}},
+				Traces: Traces{Backfill: Backfill{Enabled: true}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "reconcile",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}, Projects: []string{"group/project"}},
+				Traces: Traces{Reconcile: Reconcile{Enabled: true, Interval: time.Minute, Window: time.Hour, Delay: time.Minute}},
+			},
+		},
+		{
+			name: "reconcile without projects",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}},
+				Traces: Traces{Reconcile: Reconcile{Enabled: true, Interval: time.Minute, Window: time.Hour, Delay: time.Minute}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "reconcile without interval",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}, Projects: []string{"group/project"}},
+				Traces: Traces{Reconcile: Reconcile{Enabled: true, Window: time.Hour, Delay: time.Minute}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "reconcile with window smaller than delay",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}, Projects: []string{"group/project"}},
+				Traces: Traces{Reconcile: Reconcile{Enabled: true, Interval: time.Minute, Window: time.Minute, Delay: time.Hour}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "test reports",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}},
+				Traces: Traces{TestReports: TestReports{Enabled: true, Delay: time.Minute}},
+			},
+		},
+		{
+			name: "test reports without endpoint",
+			cfg: &Config{
+				Traces: Traces{TestReports: TestReports{Enabled: true, Delay: time.Minute}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "test reports with negative delay",
+			cfg: &Config{
+				API:    GitlabAPI{ClientConfig: confighttp.ClientConfig{Endpoint: "https://gitlab.com"}},
+				Traces: Traces{TestReports: TestReports{Enabled: true, Delay: -time.Minute}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "traces queue",
+			cfg: &Config{
+				Traces: Traces{Queue: Queue{Enabled: true, StorageID: &storageID, QueueSize: 10, RetryOnFailure: configretry.NewDefaultBackOffConfig()}},
+			},
+		},
+		{
+			name: "traces queue without storage",
+			cfg: &Config{
+				Traces: Traces{Queue: Queue{Enabled: true, QueueSize: 10}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "traces queue without size",
+			cfg: &Config{
+				Traces: Traces{Queue: Queue{Enabled: true, StorageID: &storageID}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "traces queue with invalid retry",
+			cfg: &Config{
+				Traces: Traces{Queue: Queue{Enabled: true, StorageID: &storageID, QueueSize: 10, RetryOnFailure: configretry.BackOffConfig{Enabled: true, Multiplier: -1}}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "empty secret token",
+			cfg: &Config{
+				SecretTokens: []configopaque.String{"secret", ""},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,7 +1,8 @@
 package gitlabreceiver
 
 const (
-	gitlabEventTimeFormat = "2006-01-02 15:04:05 UTC" //iso8601Format
+	gitlabEventTimeFormat = "2006-01-02 15:04:05 UTC"   //iso8601Format
+	gitlabHookTimeFormat  = "2006-01-02 15:04:05 -0700" //Time format of deployment events
 
 	//Semconv 1.27.0: https://opentelemetry.io/docs/specs/semconv/attributes-registry/cicd/#cicd-pipeline-attributes
 	conventionsAttributeCiCdPipelineName     = "cicd.pipeline.name"
@@ -11,6 +12,8 @@ const (
 	conventionsAttributeCiCdTaskRunId  = "cicd.pipeline.task.run.id"
 	conventionsAttributeCiCdTaskRunUrl = "cicd.pipeline.task.run.url.full"
 
+	conventionsAttributeDeploymentEnvironmentName = "deployment.environment.name"
+
 	//Custom Attributes - not part of Semconv 1.27.0
 
 	//General
@@ -24,6 +27,11 @@ const (
 
 	//Pipeline
 	conventionsAttributeCiCdPipelineUrl            = "cicd.pipeline.url"
+	conventionsAttributeCiCdPipelineRef            = "cicd.pipeline.ref"
+	conventionsAttributeCiCdPipelineStatus         = "cicd.pipeline.status"
+	conventionsAttributeCiCdPipelineSource         = "cicd.pipeline.source"
+	conventionsAttributeCiCdPipelineAttempt        = "cicd.pipeline.run.attempt"
+	conventionsAttributeCiCdPipelineLinkType       = "cicd.pipeline.link.type"
 	conventionsAttributeCiCdParentPipelineId       = "cicd.parent.pipeline.run.id"
 	conventionsAttributeCiCdParentPipelineUrl      = "cicd.parent.pipeline.url"
 	conventionsAttributeCiCdPipelineDuration       = "cicd.pipeline.duration"
@@ -41,11 +49,58 @@ const (
 
 	//Job
 	conventionsAttributeCiCdJobName              = "cicd.job.name"
+	conventionsAttributeCiCdJobStage             = "cicd.job.stage"
+	conventionsAttributeCiCdJobStatus            = "cicd.job.status"
 	conventionsAttributeCiCdJobEnvironment       = "cicd.job.environment"
 	conventionsAttributeCiCdJobDuration          = "cicd.job.duration"
+	conventionsAttributeCiCdJobQueuedDuration    = "cicd.job.queued.duration"
+	conventionsAttributeCiCdJobFailureReason     = "cicd.job.failure.reason"
+	conventionsAttributeCiCdJobRetriesCount      = "cicd.job.retries.count"
+	conventionsAttributeCiCdJobPhase             = "cicd.job.phase"
 	conventionsAttributeCiCdJobRunnerId          = "cicd.job.runner.id"
 	conventionsAttributeCiCdJobRunnerDescription = "cicd.job.runner.description"
 	conventionsAttributeCiCdJobRunnerIsActive    = "cicd.job.runner.active"
 	conventionsAttributeCiCdJobRunnerIsShared    = "cicd.job.runner.shared"
+	conventionsAttributeCiCdJobRunnerType        = "cicd.job.runner.type"
 	conventionsAttributeCiCdJobRunnerTag         = "cicd.job.runner.tag"
+
+	//Merge requests
+	conventionsAttributeMergeRequestId           = "cicd.merge_request.id"
+	conventionsAttributeMergeRequestIid          = "cicd.merge_request.iid"
+	conventionsAttributeMergeRequestTitle        = "cicd.merge_request.title"
+	conventionsAttributeMergeRequestUrl          = "cicd.merge_request.url"
+	conventionsAttributeMergeRequestSourceBranch = "cicd.merge_request.source_branch"
+	conventionsAttributeMergeRequestTargetBranch = "cicd.merge_request.target_branch"
+	conventionsAttributeMergeRequestState        = "cicd.merge_request.state"
+	conventionsAttributeMergeRequestMergeStatus  = "cicd.merge_request.detailed_merge_status"
+	conventionsAttributeMergeRequestAction       = "cicd.merge_request.action"
+	conventionsAttributeMergeRequestLabels       = "cicd.merge_request.labels"
+	conventionsAttributeMergeRequestAuthorId     = "cicd.merge_request.author.id"
+	conventionsAttributeMergeRequestUser         = "cicd.merge_request.user"
+
+	//Test reports - test.suite.name and test.case.name are part of Semconv 1.27.0
+	conventionsAttributeTestSuiteName         = "test.suite.name"
+	conventionsAttributeTestSuiteDuration     = "test.suite.duration"
+	conventionsAttributeTestSuiteTotalCount   = "test.suite.total.count"
+	conventionsAttributeTestSuiteSuccessCount = "test.suite.success.count"
+	conventionsAttributeTestSuiteFailedCount  = "test.suite.failed.count"
+	conventionsAttributeTestSuiteSkippedCount = "test.suite.skipped.count"
+	conventionsAttributeTestSuiteErrorCount   = "test.suite.error.count"
+	conventionsAttributeTestSuiteError        = "test.suite.error"
+	conventionsAttributeTestCaseName          = "test.case.name"
+	conventionsAttributeTestCaseClassname     = "test.case.classname"
+	conventionsAttributeTestCaseFile          = "test.case.file"
+	conventionsAttributeTestCaseStatus        = "test.case.status"
+	conventionsAttributeTestCaseDuration      = "test.case.duration"
+	conventionsAttributeTestCaseSystemOutput  = "test.case.system_output"
+	conventionsAttributeTestCaseStackTrace    = "test.case.stack_trace"
+
+	//Deployment
+	conventionsAttributeDeploymentId              = "deployment.id"
+	conventionsAttributeDeploymentStatus          = "deployment.status"
+	conventionsAttributeDeploymentEnvironmentTier = "deployment.environment.tier"
+	conventionsAttributeDeploymentEnvironmentUrl  = "deployment.environment.url"
+	conventionsAttributeDeploymentCommitTitle     = "deployment.commit.title"
+	conventionsAttributeDeploymentCommitUrl       = "deployment.commit.url"
+	conventionsAttributeDeploymentUser            = "deployment.user"
 )
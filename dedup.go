@@ -0,0 +1,87 @@
+package gitlabreceiver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+const (
+	gitlabEventUUIDHeader = "X-Gitlab-Event-UUID"
+	defaultDedupTTL       = time.Hour
+	defaultDedupEntries   = 10000
+)
+
+type deliveryState int
+
+const (
+	deliveryInFlight deliveryState = iota + 1
+	deliveryHandled
+)
+
+// deliveries remembers the handled webhooks per signal, so that redelivered webhooks (retries of Gitlab or manual resends)
+// aren't exported again. Webhooks which are still being handled are remembered as in flight.
+type deliveries struct {
+	handled *ttlCache[string, deliveryState]
+}
+
+func newDeliveries(cfg Dedup) *deliveries {
+	return &deliveries{handled: newTTLCache[string, deliveryState](cfg.MaxEntries, cfg.TTL)}
+}
+
+// Webhooks are identified by the X-Gitlab-Event-UUID header, which is the same for every delivery of an event. Finished
+// pipelines are identified by their id, status and finished time if the header is missing. Other events without the header
+// can't be deduplicated and an empty key is returned.
+//
+// Signals with different url paths are usually configured as separate webhooks in Gitlab which might share the same event uuid,
+// therefore the url path is part of the key.
+func deliveryKeyOf(req *http.Request, event gitlabResource) string {
+	if uuid := req.Header.Get(gitlabEventUUIDHeader); uuid != "" {
+		return fmt.Sprintf("%s/uuid/%s", req.URL.Path, uuid)
+	}
+	if p, ok := event.(*glPipelineEvent); ok && p.Pipeline.FinishedAt != "" {
+		return fmt.Sprintf("%s/pipeline/%d/%s/%s", req.URL.Path, p.Pipeline.Id, p.Pipeline.Status, p.Pipeline.FinishedAt)
+	}
+	return ""
+}
+
+func signalDeliveryKey(key string, signal pipeline.Signal) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/" + signal.String()
+}
+
+// claim atomically marks the delivery as in flight. If the delivery is already in flight or handled, the delivery isn't
+// claimed and its state is returned. Deliveries without key are always claimed.
+func (d *deliveries) claim(key string) (deliveryState, bool) {
+	if d == nil || key == "" {
+		return 0, true
+	}
+	var existing deliveryState
+	claimed := false
+	d.handled.update(key, func(state deliveryState, found bool) (deliveryState, bool) {
+		if found {
+			existing = state
+			return state, false
+		}
+		claimed = true
+		return deliveryInFlight, true
+	})
+	return existing, claimed
+}
+
+// Failed deliveries are released, so that they are handled again if Gitlab retries them
+func (d *deliveries) release(key string) {
+	if d != nil && key != "" {
+		d.handled.delete(key)
+	}
+}
+
+func (d *deliveries) setHandled(key string) {
+	if d != nil && key != "" {
+		d.handled.set(key, deliveryHandled)
+	}
+}
@@ -0,0 +1,87 @@
+package gitlabreceiver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+func TestDeliveryKeyOf(t *testing.T) {
+	running := newFinishedPipelineEvent()
+	running.Pipeline.Status = "running"
+	running.Pipeline.FinishedAt = ""
+
+	tests := []struct {
+		name     string
+		uuid     string
+		event    gitlabResource
+		expected string
+	}{
+		{
+			name:     "event uuid",
+			uuid:     "9cb8e8c4-2a39-4b1f-a0a4-0b1f4c2b1c11",
+			event:    newFinishedPipelineEvent(),
+			expected: "/v0.1/traces/uuid/9cb8e8c4-2a39-4b1f-a0a4-0b1f4c2b1c11",
+		},
+		{
+			name:     "finished pipeline without event uuid",
+			event:    newFinishedPipelineEvent(),
+			expected: "/v0.1/traces/pipeline/1/failed/" + gitlabEndTime,
+		},
+		{
+			name:  "running pipeline without event uuid",
+			event: running,
+		},
+		{
+			name:  "job without event uuid",
+			event: &glJobEvent{Id: 11, PipelineId: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, defaultTracesUrlPath, nil)
+			if tc.uuid != "" {
+				req.Header.Set(gitlabEventUUIDHeader, tc.uuid)
+			}
+			assert.Equal(t, tc.expected, deliveryKeyOf(req, tc.event))
+		})
+	}
+}
+
+func TestDeliveries(t *testing.T) {
+	d := newDeliveries(Dedup{Enabled: true, TTL: defaultDedupTTL, MaxEntries: 1})
+	_, ok := d.claim("first")
+	assert.True(t, ok)
+
+	//A delivery is claimed once, while it is in flight and after it was handled
+	state, ok := d.claim("first")
+	assert.False(t, ok)
+	assert.Equal(t, deliveryInFlight, state)
+	d.setHandled("first")
+	state, ok = d.claim("first")
+	assert.False(t, ok)
+	assert.Equal(t, deliveryHandled, state)
+
+	//Released deliveries can be claimed again
+	_, ok = d.claim("second")
+	assert.True(t, ok)
+	d.release("second")
+	_, ok = d.claim("second")
+	assert.True(t, ok)
+
+	//The oldest delivery is evicted if the cache is full
+	_, ok = d.claim("first")
+	assert.True(t, ok)
+
+	//Events without key are always claimed
+	_, ok = d.claim("")
+	assert.True(t, ok)
+	_, ok = d.claim("")
+	assert.True(t, ok)
+	assert.Equal(t, "", signalDeliveryKey("", pipeline.SignalTraces))
+	assert.Equal(t, "/v0.1/traces/uuid/1/traces", signalDeliveryKey("/v0.1/traces/uuid/1", pipeline.SignalTraces))
+}
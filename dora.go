@@ -0,0 +1,338 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const (
+	metricDoraDeployments       = "cicd.dora.deployment.count"
+	metricDoraLeadTime          = "cicd.dora.change.lead_time"
+	metricDoraChangeFailureRate = "cicd.dora.change.failure.rate"
+	metricDoraTimeToRestore     = "cicd.dora.restore.duration"
+
+	doraStorageName          = "dora"
+	doraMaxJobIds            = 1000
+	productionDeploymentTier = "production"
+	// Cached states of project environments, evicted states are loaded from the storage again
+	doraMaxStates = 10000
+	doraStateTTL  = 30 * 24 * time.Hour
+)
+
+// Bucket boundaries in seconds, lead times and restore times usually take between a few minutes and a few weeks
+var doraBuckets = []float64{300, 900, 1800, 3600, 10800, 21600, 43200, 86400, 172800, 604800, 1209600, 2592000}
+
+// doraState is the persisted state of a single project environment. All values are cumulative since StartTime.
+type doraState struct {
+	StartTime pcommon.Timestamp `json:"start_time"`
+	// Timestamp of the latest deployment, the data points don't go back in time if an older deployment is tracked later
+	Timestamp pcommon.Timestamp `json:"timestamp"`
+	// Ids of the tracked deployment jobs in ascending order. Pipelines don't finish in the order of their job ids,
+	// therefore every tracked job is remembered. Beyond doraMaxJobIds the lowest ids are dropped, jobs up to the
+	// highest dropped id are considered as tracked.
+	JobIds            []int          `json:"job_ids"`
+	DroppedJobId      int            `json:"dropped_job_id"`
+	Deployments       int64          `json:"deployments"`
+	FailedDeployments int64          `json:"failed_deployments"`
+	LeadTime          histogramState `json:"lead_time"`
+	TimeToRestore     histogramState `json:"time_to_restore"`
+	// Finish time of the first failed deployment after the last successful deployment, 0 if the environment is healthy
+	FailedSince pcommon.Timestamp `json:"failed_since"`
+}
+
+type histogramState struct {
+	Count        uint64   `json:"count"`
+	Sum          float64  `json:"sum"`
+	BucketCounts []uint64 `json:"bucket_counts"`
+}
+
+func (h *histogramState) record(value float64) {
+	if len(h.BucketCounts) != len(doraBuckets)+1 {
+		h.BucketCounts = make([]uint64, len(doraBuckets)+1)
+	}
+	h.Count++
+	h.Sum += value
+	h.BucketCounts[bucketIndex(doraBuckets, value)]++
+}
+
+// doraTracker correlates the deployments of a project environment. The states are cached in memory, the storage client
+// is only read if the state of a project environment isn't cached (anymore).
+type doraTracker struct {
+	mu     sync.Mutex
+	cfg    Dora
+	logger *zap.Logger
+	client storage.Client
+	states *ttlCache[string, *doraState]
+}
+
+type deployment struct {
+	job        Job
+	finishedAt pcommon.Timestamp
+}
+
+func newDoraTracker(cfg Dora, logger *zap.Logger) *doraTracker {
+	return &doraTracker{
+		cfg:    cfg,
+		logger: logger,
+		client: storage.NewNopClient(),
+		states: newTTLCache[string, *doraState](doraMaxStates, doraStateTTL),
+	}
+}
+
+func (d *doraTracker) close(ctx context.Context) error {
+	return d.client.Close(ctx)
+}
+
+// A deployment is a finished job which starts a production environment. Successful deployments count towards the
+// deployment frequency and the lead time for changes, failed deployments towards the change failure rate. The time to
+// restore service is the time between the first failed deployment and the next successful deployment of the environment.
+//
+// The ids of the tracked deployment jobs are remembered per environment. This makes sure that deployments are only
+// counted once, even if the pipeline event is received multiple times (e.g. after a retry).
+func (d *doraTracker) track(ctx context.Context, p *glPipelineEvent) (pmetric.Metrics, error) {
+	metrics := pmetric.NewMetrics()
+
+	deployments, err := d.deployments(p)
+	if err != nil || len(deployments) == 0 {
+		return metrics, err
+	}
+
+	// Lead time is measured from the commit which triggered the pipeline, earlier commits of the same deployment aren't known
+	committedAt, err := parseGitlabTime(p.Commit.Timestamp)
+	if err != nil {
+		return metrics, err
+	}
+
+	rm := metrics.ResourceMetrics().AppendEmpty()
+	setProjectResource(rm.Resource(), p.Project)
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(typeStr.String() + "receiver")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, environment := range sortedKeys(deployments) {
+		key := fmt.Sprintf("%d/%s", p.Project.Id, environment)
+		state, err := d.load(ctx, key)
+		if err != nil {
+			return metrics, err
+		}
+
+		changed := false
+		for _, dep := range deployments[environment] {
+			if state.isTracked(dep.job.Id) {
+				d.logger.Debug("Deployment was already tracked", zap.String("key", key), zap.Int("job", dep.job.Id))
+				continue
+			}
+			if state.StartTime == 0 {
+				state.StartTime = dep.finishedAt
+			}
+			state.apply(dep, committedAt)
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+
+		d.states.set(key, state)
+		// The metrics are still exported if the state can't be persisted, the state is persisted again with the next deployment
+		if err := d.persist(ctx, key, state); err != nil {
+			d.logger.Warn("Unable to persist the DORA state", zap.String("key", key), zap.Error(err))
+		}
+		state.appendMetrics(sm, environment, state.Timestamp)
+	}
+
+	if sm.Metrics().Len() == 0 {
+		return pmetric.NewMetrics(), nil
+	}
+	return metrics, nil
+}
+
+func (s *doraState) isTracked(jobId int) bool {
+	if jobId <= s.DroppedJobId {
+		return true
+	}
+	_, found := slices.BinarySearch(s.JobIds, jobId)
+	return found
+}
+
+func (s *doraState) addJobId(jobId int) {
+	i, found := slices.BinarySearch(s.JobIds, jobId)
+	if found || jobId <= s.DroppedJobId {
+		return
+	}
+	s.JobIds = slices.Insert(s.JobIds, i, jobId)
+	if len(s.JobIds) > doraMaxJobIds {
+		s.DroppedJobId = s.JobIds[0]
+		s.JobIds = slices.Delete(s.JobIds, 0, 1)
+	}
+}
+
+func (s *doraState) apply(dep deployment, committedAt pcommon.Timestamp) {
+	s.addJobId(dep.job.Id)
+	if dep.finishedAt > s.Timestamp {
+		s.Timestamp = dep.finishedAt
+	}
+	if dep.job.Status == "failed" {
+		s.FailedDeployments++
+		if s.FailedSince == 0 {
+			s.FailedSince = dep.finishedAt
+		}
+		return
+	}
+
+	s.Deployments++
+	if committedAt != 0 && dep.finishedAt > committedAt {
+		s.LeadTime.record(dep.finishedAt.AsTime().Sub(committedAt.AsTime()).Seconds())
+	}
+	if s.FailedSince != 0 {
+		s.TimeToRestore.record(dep.finishedAt.AsTime().Sub(s.FailedSince.AsTime()).Seconds())
+		s.FailedSince = 0
+	}
+}
+
+func (s *doraState) appendMetrics(sm pmetric.ScopeMetrics, environment string, ts pcommon.Timestamp) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr(conventionsAttributeDeploymentEnvironmentName, environment)
+
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(metricDoraDeployments)
+	m.SetDescription("Number of deployments to the environment")
+	m.SetUnit("{deployment}")
+	sum := m.SetEmptySum()
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	sum.SetIsMonotonic(true)
+	counts := []struct {
+		status string
+		value  int64
+	}{{"success", s.Deployments}, {"failed", s.FailedDeployments}}
+	for _, c := range counts {
+		dp := sum.DataPoints().AppendEmpty()
+		attrs.CopyTo(dp.Attributes())
+		dp.Attributes().PutStr(conventionsAttributeCiCdJobStatus, c.status)
+		dp.SetStartTimestamp(s.StartTime)
+		dp.SetTimestamp(ts)
+		dp.SetIntValue(c.value)
+	}
+
+	m = sm.Metrics().AppendEmpty()
+	m.SetName(metricDoraChangeFailureRate)
+	m.SetDescription("Ratio of deployments to the environment which failed")
+	m.SetUnit("1")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	attrs.CopyTo(dp.Attributes())
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(float64(s.FailedDeployments) / float64(s.Deployments+s.FailedDeployments))
+
+	s.LeadTime.appendMetric(sm, metricDoraLeadTime, "Time between the commit and its successful deployment to the environment", attrs, s.StartTime, ts)
+	s.TimeToRestore.appendMetric(sm, metricDoraTimeToRestore, "Time between a failed deployment and the next successful deployment to the environment", attrs, s.StartTime, ts)
+}
+
+func (h histogramState) appendMetric(sm pmetric.ScopeMetrics, name string, description string, attrs pcommon.Map, start pcommon.Timestamp, ts pcommon.Timestamp) {
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(name)
+	m.SetDescription(description)
+	m.SetUnit("s")
+	histogram := m.SetEmptyHistogram()
+	histogram.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+	dp := histogram.DataPoints().AppendEmpty()
+	attrs.CopyTo(dp.Attributes())
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(ts)
+	dp.SetCount(h.Count)
+	dp.SetSum(h.Sum)
+	dp.ExplicitBounds().FromRaw(doraBuckets)
+	bucketCounts := h.BucketCounts
+	if len(bucketCounts) == 0 {
+		bucketCounts = make([]uint64, len(doraBuckets)+1)
+	}
+	dp.BucketCounts().FromRaw(bucketCounts)
+}
+
+// Deployments of the pipeline by environment, ordered by job id
+func (d *doraTracker) deployments(p *glPipelineEvent) (map[string][]deployment, error) {
+	deployments := make(map[string][]deployment)
+	for _, j := range p.Jobs {
+		if !d.isProduction(j.Environment) || j.FinishedAt == "" || (j.Status != "success" && j.Status != "failed") {
+			continue
+		}
+		// Stopping an environment or preparing a deployment isn't a deployment
+		if j.Environment.Action != "" && j.Environment.Action != "start" {
+			continue
+		}
+
+		finishedAt, err := parseGitlabTime(j.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		deployments[j.Environment.Name] = append(deployments[j.Environment.Name], deployment{job: j, finishedAt: finishedAt})
+	}
+
+	for _, deps := range deployments {
+		slices.SortFunc(deps, func(a, b deployment) int {
+			return a.job.Id - b.job.Id
+		})
+	}
+	return deployments, nil
+}
+
+func (d *doraTracker) isProduction(env Environment) bool {
+	if env.Name == "" {
+		return false
+	}
+	if len(d.cfg.Environments) > 0 {
+		return slices.Contains(d.cfg.Environments, env.Name)
+	}
+	return env.DeploymentTier == productionDeploymentTier
+}
+
+// A copy of the state is returned, so that the cached state is only replaced once the deployments were applied
+func (d *doraTracker) load(ctx context.Context, key string) (*doraState, error) {
+	if state, ok := d.states.get(key); ok {
+		s := *state
+		s.JobIds = slices.Clone(state.JobIds)
+		s.LeadTime.BucketCounts = slices.Clone(state.LeadTime.BucketCounts)
+		s.TimeToRestore.BucketCounts = slices.Clone(state.TimeToRestore.BucketCounts)
+		return &s, nil
+	}
+
+	data, err := d.client.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the DORA state: %w", err)
+	}
+	state := &doraState{}
+	if data == nil {
+		return state, nil
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unable to decode the DORA state: %w", err)
+	}
+	return state, nil
+}
+
+func (d *doraTracker) persist(ctx context.Context, key string, state *doraState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return d.client.Set(ctx, key, data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,254 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func TestDoraTrackerTrack(t *testing.T) {
+	tracker := newDoraTracker(Dora{Enabled: true}, zap.NewNop())
+
+	//A failed deployment followed by a successful deployment one hour later
+	failed := newDeploymentPipelineEvent(21, "failed", "2024-01-01 12:00:00 UTC")
+	metrics, err := tracker.track(context.Background(), failed)
+	require.NoError(t, err)
+	got := collectMetrics(metrics)
+	assert.Equal(t, 1.0, got[metricDoraChangeFailureRate].Gauge().DataPoints().At(0).DoubleValue())
+	assert.Equal(t, uint64(0), got[metricDoraTimeToRestore].Histogram().DataPoints().At(0).Count())
+
+	success := newDeploymentPipelineEvent(22, "success", "2024-01-01 13:00:00 UTC")
+	metrics, err = tracker.track(context.Background(), success)
+	require.NoError(t, err)
+	got = collectMetrics(metrics)
+
+	deployments := got[metricDoraDeployments].Sum()
+	assert.Equal(t, pmetric.AggregationTemporalityCumulative, deployments.AggregationTemporality())
+	require.Equal(t, 2, deployments.DataPoints().Len())
+	assert.Equal(t, int64(1), deployments.DataPoints().At(0).IntValue())
+	assert.Equal(t, int64(1), deployments.DataPoints().At(1).IntValue())
+	env, _ := deployments.DataPoints().At(0).Attributes().Get(conventionsAttributeDeploymentEnvironmentName)
+	assert.Equal(t, "production", env.Str())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:00:00 UTC"), deployments.DataPoints().At(0).StartTimestamp())
+
+	assert.Equal(t, 0.5, got[metricDoraChangeFailureRate].Gauge().DataPoints().At(0).DoubleValue())
+
+	//The commit was created 2 hours before the successful deployment
+	leadTime := got[metricDoraLeadTime].Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(1), leadTime.Count())
+	assert.Equal(t, float64(7200), leadTime.Sum())
+
+	timeToRestore := got[metricDoraTimeToRestore].Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(1), timeToRestore.Count())
+	assert.Equal(t, float64(3600), timeToRestore.Sum())
+
+	//Deployments are only counted once
+	metrics, err = tracker.track(context.Background(), success)
+	require.NoError(t, err)
+	assert.Equal(t, 0, metrics.DataPointCount())
+}
+
+func TestDoraTrackerEnvironments(t *testing.T) {
+	tests := []struct {
+		name         string
+		environments []string
+		environment  Environment
+		expected     bool
+	}{
+		{
+			name:        "production deployment tier",
+			environment: Environment{Name: "prod-eu", DeploymentTier: "production"},
+			expected:    true,
+		},
+		{
+			name:        "staging deployment tier",
+			environment: Environment{Name: "staging", DeploymentTier: "staging"},
+		},
+		{
+			name:         "configured environment",
+			environments: []string{"staging"},
+			environment:  Environment{Name: "staging", DeploymentTier: "staging"},
+			expected:     true,
+		},
+		{
+			name:         "not configured environment",
+			environments: []string{"staging"},
+			environment:  Environment{Name: "production", DeploymentTier: "production"},
+		},
+		{
+			name:        "stopped environment",
+			environment: Environment{Name: "production", Action: "stop", DeploymentTier: "production"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newDoraTracker(Dora{Enabled: true, Environments: tc.environments}, zap.NewNop())
+			p := newDeploymentPipelineEvent(21, "success", "2024-01-01 12:00:00 UTC")
+			p.Jobs[0].Environment = tc.environment
+
+			metrics, err := tracker.track(context.Background(), p)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, metrics.DataPointCount() > 0)
+		})
+	}
+}
+
+func TestDoraTrackerOutOfOrder(t *testing.T) {
+	tracker := newDoraTracker(Dora{Enabled: true}, zap.NewNop())
+
+	//The pipeline of job 15 started earlier but finished after the pipeline of job 20
+	_, err := tracker.track(context.Background(), newDeploymentPipelineEvent(20, "success", "2024-01-01 13:00:00 UTC"))
+	require.NoError(t, err)
+	metrics, err := tracker.track(context.Background(), newDeploymentPipelineEvent(15, "success", "2024-01-01 14:00:00 UTC"))
+	require.NoError(t, err)
+	got := collectMetrics(metrics)
+	assert.Equal(t, int64(2), got[metricDoraDeployments].Sum().DataPoints().At(0).IntValue())
+	assert.Equal(t, uint64(2), got[metricDoraLeadTime].Histogram().DataPoints().At(0).Count())
+
+	//Both deployments are only counted once
+	for _, jobId := range []int{15, 20} {
+		metrics, err = tracker.track(context.Background(), newDeploymentPipelineEvent(jobId, "success", "2024-01-01 14:00:00 UTC"))
+		require.NoError(t, err)
+		assert.Equal(t, 0, metrics.DataPointCount())
+	}
+
+	//A deployment which finished before the latest deployment doesn't move the timestamp back
+	metrics, err = tracker.track(context.Background(), newDeploymentPipelineEvent(10, "success", "2024-01-01 12:30:00 UTC"))
+	require.NoError(t, err)
+	got = collectMetrics(metrics)
+	assert.Equal(t, int64(3), got[metricDoraDeployments].Sum().DataPoints().At(0).IntValue())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 14:00:00 UTC"), got[metricDoraDeployments].Sum().DataPoints().At(0).Timestamp())
+}
+
+func TestDoraStateJobIds(t *testing.T) {
+	state := &doraState{}
+	for jobId := doraMaxJobIds + 10; jobId > 0; jobId-- {
+		state.addJobId(jobId)
+	}
+	assert.Len(t, state.JobIds, doraMaxJobIds)
+	assert.Equal(t, 10, state.DroppedJobId)
+	assert.True(t, state.isTracked(1), "jobs up to the dropped ids are considered as tracked")
+	assert.True(t, state.isTracked(doraMaxJobIds+10))
+	assert.False(t, state.isTracked(doraMaxJobIds+11))
+}
+
+func TestDoraTrackerPersistence(t *testing.T) {
+	client := newMemoryStorageClient()
+	tracker := newDoraTracker(Dora{Enabled: true}, zap.NewNop())
+	tracker.client = client
+
+	_, err := tracker.track(context.Background(), newDeploymentPipelineEvent(21, "success", "2024-01-01 12:00:00 UTC"))
+	require.NoError(t, err)
+
+	//A restarted receiver continues counting with the persisted state
+	restarted := newDoraTracker(Dora{Enabled: true}, zap.NewNop())
+	restarted.client = client
+
+	metrics, err := restarted.track(context.Background(), newDeploymentPipelineEvent(21, "success", "2024-01-01 12:00:00 UTC"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, metrics.DataPointCount(), "deployments tracked before the restart are not counted again")
+
+	metrics, err = restarted.track(context.Background(), newDeploymentPipelineEvent(22, "success", "2024-01-01 13:00:00 UTC"))
+	require.NoError(t, err)
+	got := collectMetrics(metrics)
+	assert.Equal(t, int64(2), got[metricDoraDeployments].Sum().DataPoints().At(0).IntValue())
+	assert.Equal(t, uint64(2), got[metricDoraLeadTime].Histogram().DataPoints().At(0).Count())
+}
+
+func TestDoraTrackerEvictedState(t *testing.T) {
+	tracker := newDoraTracker(Dora{Enabled: true}, zap.NewNop())
+	tracker.client = newMemoryStorageClient()
+	tracker.states = newTTLCache[string, *doraState](1, doraStateTTL)
+
+	_, err := tracker.track(context.Background(), newDeploymentPipelineEvent(21, "success", "2024-01-01 12:00:00 UTC"))
+	require.NoError(t, err)
+
+	//The deployment of another project evicts the cached state, which is loaded from the storage again
+	other := newDeploymentPipelineEvent(30, "success", "2024-01-01 12:30:00 UTC")
+	other.Project.Id = 43
+	_, err = tracker.track(context.Background(), other)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tracker.states.len())
+
+	metrics, err := tracker.track(context.Background(), newDeploymentPipelineEvent(22, "success", "2024-01-01 13:00:00 UTC"))
+	require.NoError(t, err)
+	got := collectMetrics(metrics)
+	assert.Equal(t, int64(2), got[metricDoraDeployments].Sum().DataPoints().At(0).IntValue())
+}
+
+func newDeploymentPipelineEvent(jobId int, status string, finishedAt string) *glPipelineEvent {
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Status = status
+	p.Pipeline.FinishedAt = finishedAt
+	p.Commit = Commit{ID: "abc123", Timestamp: "2024-01-01T11:00:00Z"}
+	p.Jobs = []Job{
+		{
+			Id:          jobId,
+			Name:        "deploy",
+			Stage:       "deploy",
+			Status:      status,
+			StartedAt:   gitlabStartTime,
+			FinishedAt:  finishedAt,
+			Environment: Environment{Name: "production", Action: "start", DeploymentTier: "production"},
+		},
+	}
+	return p
+}
+
+type memoryStorageClient struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryStorageClient() *memoryStorageClient {
+	return &memoryStorageClient{data: make(map[string][]byte)}
+}
+
+func (c *memoryStorageClient) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data[key], nil
+}
+
+func (c *memoryStorageClient) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+	return nil
+}
+
+func (c *memoryStorageClient) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.data, key)
+	return nil
+}
+
+func (c *memoryStorageClient) Batch(ctx context.Context, ops ...storage.Operation) error {
+	for _, op := range ops {
+		var err error
+		switch op.Type {
+		case storage.Get:
+			op.Value, err = c.Get(ctx, op.Key)
+		case storage.Set:
+			err = c.Set(ctx, op.Key, op.Value)
+		case storage.Delete:
+			err = c.Delete(ctx, op.Key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memoryStorageClient) Close(context.Context) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+const (
+	gitlabEventHeader = "X-Gitlab-Event"
+	// System hooks are sent with the same header for all event types, the event type is defined by the object_kind of the body
+	systemHookHeader = "System Hook"
+
+	pipelineEventKind   = "pipeline"
+	jobEventKind        = "build"
+	deploymentEventKind = "deployment"
+	// Merge request hooks use the same object_kind for all actions
+	mergeRequestEventKind = "merge_request"
+)
+
+var (
+	// Known Gitlab hook which isn't handled by the receiver, answered with 202 so that Gitlab doesn't disable the webhook
+	errUnsupportedEvent = errors.New("unsupported event type")
+	// The event was handled but intentionally not exported (e.g. because of the configured refs)
+	errNotExported = errors.New("event is not configured to be exported")
+)
+
+type eventHandlerFunc func(glRcvr *gitlabReceiver, ctx context.Context, event gitlabResource) error
+
+// eventHandler describes how a Gitlab hook is decoded and which telemetry is created out of it.
+// To support an additional hook, the hook needs to be added to the eventHandlers registry.
+type eventHandler struct {
+	header  string
+	decode  func(body []byte) (gitlabResource, error)
+	traces  eventHandlerFunc
+	metrics eventHandlerFunc
+	logs    eventHandlerFunc
+}
+
+func (h eventHandler) signal(signal pipeline.Signal) eventHandlerFunc {
+	switch signal {
+	case pipeline.SignalTraces:
+		return h.traces
+	case pipeline.SignalMetrics:
+		return h.metrics
+	case pipeline.SignalLogs:
+		return h.logs
+	}
+	return nil
+}
+
+// If the event doesn't create telemetry for any of the signals, it is handled like an unsupported event
+func (h eventHandler) supports(signals []pipeline.Signal) bool {
+	for _, s := range signals {
+		if h.signal(s) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry of all supported Gitlab hooks by object_kind
+var eventHandlers = map[string]eventHandler{
+	pipelineEventKind: {
+		header:  "Pipeline Hook",
+		decode:  decodeEvent[*glPipelineEvent],
+		traces:  handle((*gitlabReceiver).handlePipelineTraces),
+		metrics: handle((*gitlabReceiver).handlePipelineMetrics),
+		logs:    handle((*gitlabReceiver).handlePipelineLogs),
+	},
+	jobEventKind: {
+		header: "Job Hook",
+		decode: decodeEvent[*glJobEvent],
+		traces: handle((*gitlabReceiver).handleJobTraces),
+		logs:   handle((*gitlabReceiver).handleJobLogs),
+	},
+	deploymentEventKind: {
+		header: "Deployment Hook",
+		decode: decodeEvent[*glDeploymentEvent],
+		traces: handle((*gitlabReceiver).handleDeploymentTraces),
+		logs:   handle((*gitlabReceiver).handleDeploymentLogs),
+	},
+	mergeRequestEventKind: {
+		header: "Merge Request Hook",
+		decode: decodeEvent[*glMergeRequestEvent],
+		traces: handle((*gitlabReceiver).handleMergeRequestTraces),
+		logs:   handle((*gitlabReceiver).handleMergeRequestLogs),
+	},
+}
+
+// The header of a registered hook or the system hook header, otherwise "unknown"
+func knownEventHeader(header string) string {
+	if header == systemHookHeader {
+		return header
+	}
+	for _, h := range eventHandlers {
+		if h.header == header {
+			return header
+		}
+	}
+	return eventUnknown
+}
+
+// The event handler is determined by the X-Gitlab-Event header. For system hooks the object_kind of the body is used instead.
+// If both are available, they need to match.
+func getEventHandler(req *http.Request, body []byte) (eventHandler, error) {
+	header := req.Header.Get(gitlabEventHeader)
+	if header == "" {
+		return eventHandler{}, fmt.Errorf("missing %s header", gitlabEventHeader)
+	}
+
+	kind, err := peekObjectKind(body)
+	if err != nil {
+		return eventHandler{}, err
+	}
+
+	if header == systemHookHeader {
+		h, ok := eventHandlers[kind]
+		if !ok {
+			return eventHandler{}, fmt.Errorf("%w: %s with object_kind %q", errUnsupportedEvent, header, kind)
+		}
+		return h, nil
+	}
+
+	for k, h := range eventHandlers {
+		if h.header != header {
+			continue
+		}
+		if kind != "" && kind != k {
+			return eventHandler{}, fmt.Errorf("object_kind %q doesn't match the %s header %q", kind, gitlabEventHeader, header)
+		}
+		return h, nil
+	}
+	return eventHandler{}, fmt.Errorf("%w: %s", errUnsupportedEvent, header)
+}
+
+func peekObjectKind(body []byte) (string, error) {
+	var event struct {
+		Kind string `json:"object_kind"`
+	}
+	err := json.Unmarshal(body, &event)
+	if err != nil {
+		return "", fmt.Errorf("decode json: %w", err)
+	}
+	return event.Kind, nil
+}
+
+func decodeEvent[T gitlabResource](body []byte) (gitlabResource, error) {
+	return decode[T](body)
+}
+
+// The registry is not aware of the concrete event types, the event is guaranteed to be of type T because it was decoded by the same handler
+func handle[T gitlabResource](fn func(glRcvr *gitlabReceiver, ctx context.Context, event T) error) eventHandlerFunc {
+	return func(glRcvr *gitlabReceiver, ctx context.Context, event gitlabResource) error {
+		return fn(glRcvr, ctx, event.(T))
+	}
+}
@@ -0,0 +1,108 @@
+package gitlabreceiver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		body           string
+		expectedHeader string
+		unsupported    bool
+		expectedErr    bool
+	}{
+		{
+			name:           "pipeline hook",
+			header:         "Pipeline Hook",
+			body:           `{"object_kind": "pipeline"}`,
+			expectedHeader: "Pipeline Hook",
+		},
+		{
+			name:           "job hook",
+			header:         "Job Hook",
+			body:           `{"object_kind": "build"}`,
+			expectedHeader: "Job Hook",
+		},
+		{
+			name:           "deployment hook",
+			header:         "Deployment Hook",
+			body:           `{"object_kind": "deployment"}`,
+			expectedHeader: "Deployment Hook",
+		},
+		{
+			name:           "merge request hook",
+			header:         "Merge Request Hook",
+			body:           `{"object_kind": "merge_request"}`,
+			expectedHeader: "Merge Request Hook",
+		},
+		{
+			name:           "hook without object_kind",
+			header:         "Pipeline Hook",
+			body:           `{}`,
+			expectedHeader: "Pipeline Hook",
+		},
+		{
+			name:           "system hook",
+			header:         "System Hook",
+			body:           `{"object_kind": "pipeline"}`,
+			expectedHeader: "Pipeline Hook",
+		},
+		{
+			name:        "unsupported system hook",
+			header:      "System Hook",
+			body:        `{"object_kind": "repository_update"}`,
+			unsupported: true,
+		},
+		{
+			name:        "unsupported hook",
+			header:      "Push Hook",
+			body:        `{"object_kind": "push"}`,
+			unsupported: true,
+		},
+		{
+			name:        "object_kind not matching the header",
+			header:      "Pipeline Hook",
+			body:        `{"object_kind": "build"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "missing header",
+			body:        `{"object_kind": "pipeline"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid body",
+			header:      "Pipeline Hook",
+			body:        "invalid req body",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(gitlabEventHeader, tc.header)
+
+			h, err := getEventHandler(req, []byte(tc.body))
+			switch {
+			case tc.unsupported:
+				assert.ErrorIs(t, err, errUnsupportedEvent)
+			case tc.expectedErr:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, errUnsupportedEvent)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedHeader, h.header)
+
+				_, err = h.decode([]byte(tc.body))
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -8,16 +8,43 @@ import (
 	"go.opentelemetry.io/collector/receiver"
 )
 
+var receivers = newSharedReceivers()
+
 func createTracesReceiver(ctx context.Context, settings receiver.Settings, cfg component.Config, consumer consumer.Traces) (receiver.Traces, error) {
-	glRcvr := newGitlabReceiver(cfg, settings)
+	glRcvr, err := receivers.getOrCreate(cfg, settings)
+	if err != nil {
+		return nil, err
+	}
 	glRcvr.nextTracesConsumer = consumer
 
 	return glRcvr, nil
 }
 
+func createMetricsReceiver(ctx context.Context, settings receiver.Settings, cfg component.Config, consumer consumer.Metrics) (receiver.Metrics, error) {
+	glRcvr, err := receivers.getOrCreate(cfg, settings)
+	if err != nil {
+		return nil, err
+	}
+	glRcvr.nextMetricsConsumer = consumer
+
+	return glRcvr, nil
+}
+
+func createLogsReceiver(ctx context.Context, settings receiver.Settings, cfg component.Config, consumer consumer.Logs) (receiver.Logs, error) {
+	glRcvr, err := receivers.getOrCreate(cfg, settings)
+	if err != nil {
+		return nil, err
+	}
+	glRcvr.nextLogsConsumer = consumer
+
+	return glRcvr, nil
+}
+
 func NewFactory() receiver.Factory {
 	return receiver.NewFactory(
 		typeStr,
 		createDefaultConfig,
-		receiver.WithTraces(createTracesReceiver, component.StabilityLevelDevelopment))
+		receiver.WithTraces(createTracesReceiver, component.StabilityLevelDevelopment),
+		receiver.WithMetrics(createMetricsReceiver, component.StabilityLevelDevelopment),
+		receiver.WithLogs(createLogsReceiver, component.StabilityLevelDevelopment))
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/receiver/receivertest"
 )
@@ -18,3 +20,49 @@ func TestCreateTracesReceiver(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, tReceiver, "traces receiver creation failed")
 }
+
+func TestCreateMetricsReceiver(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	assert.NotNil(t, cfg, "failed to create default config")
+
+	mReceiver, err := factory.CreateMetrics(context.Background(), receivertest.NewNopSettings(), cfg, consumertest.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, mReceiver, "metrics receiver creation failed")
+}
+
+func TestCreateLogsReceiver(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	assert.NotNil(t, cfg, "failed to create default config")
+
+	lReceiver, err := factory.CreateLogs(context.Background(), receivertest.NewNopSettings(), cfg, consumertest.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, lReceiver, "logs receiver creation failed")
+}
+
+func TestCreateSharedReceiver(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	p, err := getFreePort()
+	require.NoError(t, err, "error finding an available port")
+	cfg.Endpoint = "localhost:" + p
+
+	tReceiver, err := factory.CreateTraces(context.Background(), receivertest.NewNopSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	mReceiver, err := factory.CreateMetrics(context.Background(), receivertest.NewNopSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.Same(t, tReceiver, mReceiver, "all signals must share the same receiver")
+
+	//The shared receiver is only started and shut down once
+	host := componenttest.NewNopHost()
+	require.NoError(t, tReceiver.Start(context.Background(), host))
+	require.NoError(t, mReceiver.Start(context.Background(), host))
+	require.NoError(t, tReceiver.Shutdown(context.Background()))
+	require.NoError(t, mReceiver.Shutdown(context.Background()))
+
+	//After the shutdown a new receiver is created for the same config
+	tReceiver2, err := factory.CreateTraces(context.Background(), receivertest.NewNopSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.NotSame(t, tReceiver, tReceiver2)
+}
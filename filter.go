@@ -0,0 +1,116 @@
+package gitlabreceiver
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Patterns are globs (e.g. release/*) or regular expressions enclosed in slashes like in the Gitlab CI rules
+// (e.g. /^release\/.*$/). The regular expression is returned for the latter, globs are only validated.
+func parsePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		return re, nil
+	}
+	if pattern == "" {
+		return nil, fmt.Errorf("invalid pattern %q: must not be empty", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil, nil
+}
+
+// Events which belong to a project, all supported events do
+type projectEvent interface {
+	project() Project
+}
+
+func (p *glPipelineEvent) project() Project      { return p.Project }
+func (j *glJobEvent) project() Project           { return j.Project }
+func (d *glDeploymentEvent) project() Project    { return d.Project }
+func (mr *glMergeRequestEvent) project() Project { return mr.Project }
+
+// A project pattern is the id of a project, the path of a project or group, a glob (e.g. group/team-*) or a regular
+// expression enclosed in slashes. Paths and globs match the project and all projects within the matching groups,
+// regular expressions are matched against the path of the project.
+type projectPattern struct {
+	id      int
+	pattern string
+	regexp  *regexp.Regexp
+}
+
+func newProjectPattern(pattern string) (projectPattern, error) {
+	if id, err := strconv.Atoi(pattern); err == nil {
+		return projectPattern{id: id}, nil
+	}
+	re, err := parsePattern(pattern)
+	if err != nil {
+		return projectPattern{}, err
+	}
+	return projectPattern{pattern: strings.TrimSuffix(pattern, "/"), regexp: re}, nil
+}
+
+func (pp projectPattern) matches(p Project) bool {
+	switch {
+	case pp.id != 0:
+		return pp.id == p.Id
+	case pp.regexp != nil:
+		return pp.regexp.MatchString(p.Path)
+	}
+	for namespace := p.Path; namespace != "." && namespace != "/" && namespace != ""; namespace = path.Dir(namespace) {
+		if ok, _ := path.Match(pp.pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// projectFilter handles the events of projects which match one of the included patterns and none of the excluded
+// patterns. Without included patterns all projects are included.
+type projectFilter struct {
+	include []projectPattern
+	exclude []projectPattern
+}
+
+func newProjectFilter(include []string, exclude []string) (*projectFilter, error) {
+	f := &projectFilter{}
+	for _, pattern := range include {
+		pp, err := newProjectPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, pp)
+	}
+	for _, pattern := range exclude {
+		pp, err := newProjectPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, pp)
+	}
+	return f, nil
+}
+
+func (f *projectFilter) matches(p Project) bool {
+	for _, pp := range f.exclude {
+		if pp.matches(p) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pp := range f.include {
+		if pp.matches(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package gitlabreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestProjectPattern(t *testing.T) {
+	project := Project{Id: 42, Path: "group/subgroup/project"}
+	tests := []struct {
+		name     string
+		pattern  string
+		expected bool
+	}{
+		{name: "id", pattern: "42", expected: true},
+		{name: "other id", pattern: "43"},
+		{name: "path", pattern: "group/subgroup/project", expected: true},
+		{name: "other path", pattern: "group/subgroup/other"},
+		{name: "group", pattern: "group", expected: true},
+		{name: "subgroup", pattern: "group/subgroup/", expected: true},
+		{name: "group prefix of the name", pattern: "group/sub"},
+		{name: "glob", pattern: "group/*/project", expected: true},
+		{name: "group glob", pattern: "group/sub*", expected: true},
+		{name: "other glob", pattern: "other/*"},
+		{name: "regexp", pattern: `/^group\/.*project$/`, expected: true},
+		{name: "other regexp", pattern: "/^subgroup/"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pp, err := newProjectPattern(tc.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, pp.matches(project))
+		})
+	}
+}
+
+func TestProjectFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected bool
+	}{
+		{name: "no patterns", expected: true},
+		{name: "included", include: []string{"other", "group"}, expected: true},
+		{name: "not included", include: []string{"other"}},
+		{name: "excluded", exclude: []string{"42"}},
+		{name: "not excluded", exclude: []string{"group/other"}, expected: true},
+		{name: "group included and project excluded", include: []string{"group"}, exclude: []string{"group/project"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := newProjectFilter(tc.include, tc.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f.matches(Project{Id: 42, Path: "group/project"}))
+		})
+	}
+
+	_, err := newProjectFilter([]string{"group/["}, nil)
+	assert.Error(t, err)
+}
+
+func TestGitlabReceiverProjectFilter(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Projects.Include = []string{"group"}
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	tracesSink := new(consumertest.TracesSink)
+	logsSink := new(consumertest.LogsSink)
+	glRcvr.nextTracesConsumer = tracesSink
+	glRcvr.nextLogsConsumer = logsSink
+
+	res := sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent(), pipeline.SignalTraces, pipeline.SignalLogs)
+	assert.Equal(t, "OK", res.Body.String())
+
+	//The filter is applied to all signals and events
+	p := newFinishedPipelineEvent()
+	p.Project.Path = "other/project"
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p, pipeline.SignalTraces, pipeline.SignalLogs)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+	mr := newMergeRequestEvent("open", "2024-01-01 10:00:00 UTC")
+	mr.Project.Path = "other/project"
+	res = sendEvent(t, glRcvr, "Merge Request Hook", mr)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+
+	assert.Len(t, tracesSink.AllTraces(), 1)
+	assert.Len(t, logsSink.AllLogs(), 1)
+}
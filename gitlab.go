@@ -2,8 +2,9 @@ package gitlabreceiver
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,20 +17,55 @@ import (
 
 type gitlabResource interface {
 	newTrace() (*ptrace.Traces, error)
-	setAttributes(ptrace.Span)
+	spanResource
 }
 
-// The whole pipeline is the root span which defines the trace
-func (p *glPipelineEvent) newTrace() (*ptrace.Traces, error) {
-	var traceId [16]byte
-	var rootSpanId [8]byte
+// spanResource sets the attributes of its span, e.g. a job or a stage of the pipeline trace
+type spanResource interface {
+	setAttributes(ptrace.Span)
+}
 
-	//We generate the trace and root span id based on a hash consisting out of several (unique) values
-	traceId, err := getTraceId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.Pipeline.FinishedAt)
+// We generate the trace and root span id based on a hash consisting out of several (unique) values. The root span id
+// always depends on the finished time, so that every attempt of a pipeline has its own root span within a stable trace.
+func (p *glPipelineEvent) traceContext() ([16]byte, [8]byte, error) {
+	traceId, err := getTraceId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.hashTime())
 	if err != nil {
-		return nil, err
+		return [16]byte{}, [8]byte{}, err
+	}
+	rootSpanId, err := getRootSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.Pipeline.FinishedAt)
+	if err != nil {
+		return [16]byte{}, [8]byte{}, err
+	}
+	return traceId, rootSpanId, nil
+}
+
+// The finished time of the pipeline is part of the hash, unless stable trace ids are configured. Stable trace ids can be
+// calculated while the pipeline is running (see TraceParent), but a retried pipeline is exported into the same trace.
+// Jobs keep their span id in the stable trace because the job id is unique for every attempt of a job.
+func (p *glPipelineEvent) hashTime() string {
+	if p.stableTraceId {
+		return stableTraceIdTime
+	}
+	return p.Pipeline.FinishedAt
+}
+
+// Ids of the jobs of the pipeline event and of the stored job events
+func (p *glPipelineEvent) jobIds() []int {
+	ids := make([]int, 0, len(p.Jobs)+len(p.jobEvents))
+	for _, j := range p.Jobs {
+		ids = append(ids, j.Id)
+	}
+	for id := range p.jobEvents {
+		if !slices.ContainsFunc(p.Jobs, func(j Job) bool { return j.Id == id }) {
+			ids = append(ids, id)
+		}
 	}
-	rootSpanId, err = getRootSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.Pipeline.FinishedAt)
+	return ids
+}
+
+// The whole pipeline is the root span which defines the trace
+func (p *glPipelineEvent) newTrace() (*ptrace.Traces, error) {
+	traceId, rootSpanId, err := p.traceContext()
 	if err != nil {
 		return nil, err
 	}
@@ -58,63 +94,578 @@ func (p *glPipelineEvent) newTrace() (*ptrace.Traces, error) {
 	rs.Resource().Attributes().PutStr(conventionsAttributeCiCdRepositoryUrl, p.Project.Url)
 	rs.Resource().Attributes().PutStr(conventionsAttributeCiCdRepositoryPath, p.Project.Path)
 	rs.Resource().Attributes().PutStr(conventionsAttributeCiCdRepositoryId, strconv.Itoa(p.Project.Id))
+	if p.MergeRequest.Iid != 0 {
+		p.MergeRequest.putAttributes(rs.Resource().Attributes())
+	}
 
 	//The pipeline span is the root span, therefore 0 bytes for the parentSpanId
-	createSpan(rs, traceId, rootSpanId, [8]byte{0, 0, 0, 0, 0, 0, 0, 0}, pipelineName, startTime, endTime, p)
+	rootSpan := createSpan(rs, traceId, rootSpanId, [8]byte{0, 0, 0, 0, 0, 0, 0, 0}, pipelineName, startTime, endTime, p)
+	p.linkAttempts(rootSpan)
+	if p.upstream != nil {
+		addPipelineLink(rootSpan, *p.upstream, "upstream")
+	}
+	err = p.linkMergeRequest(rootSpan)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := p.finishedJobs()
+
+	//Without stage spans all jobs are direct children of the root span
+	stageSpanIds := map[string][8]byte{}
+	if p.stageSpans {
+		stageSpanIds, err = p.createStageSpans(rs, traceId, rootSpanId, jobs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jobSpans := make(map[int]ptrace.Span, len(jobs))
+	for _, js := range jobs {
+		parentSpanId, ok := stageSpanIds[js.job.Stage]
+		if !ok {
+			parentSpanId = rootSpanId
+		}
+
+		spanId, err := p.jobSpanId(js.job.Id)
+		if err != nil {
+			return nil, err
+		}
+		span, err := createJobSpan(rs, traceId, spanId, parentSpanId, js.job, js.res, p.jobPhases)
+		if err != nil {
+			return nil, err
+		}
+		jobSpans[js.job.Id] = span
+
+		//Job events carry details (e.g. the failure reason) which are not part of the builds of the pipeline event
+		if js.event != nil {
+			js.event.putJobEventAttributes(span.Attributes())
+		}
+
+		err = p.createDeploymentSpan(rs, traceId, span.SpanID(), js.job.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//Downstream pipelines which finished before this pipeline are linked from the span of their bridge job
+	for _, l := range p.downstream {
+		span, ok := jobSpans[l.bridgeJobId]
+		if !ok {
+			span = rootSpan
+		}
+		addPipelineLink(span, l, "downstream")
+	}
+	return &trace, nil
+}
+
+// Every attempt of a retried pipeline is exported as separate trace, the root span links to the root spans of the previous attempts.
+// With stable trace ids the attempts share the trace, but every attempt has its own root span which is linked as well.
+func (p *glPipelineEvent) linkAttempts(rootSpan ptrace.Span) {
+	attempt := p.attempt()
+	for _, a := range p.attempts.latest {
+		if a.attempt >= attempt {
+			break
+		}
+		link := rootSpan.Links().AppendEmpty()
+		link.SetTraceID(a.traceId)
+		link.SetSpanID(a.rootSpanId)
+		link.Attributes().PutInt(conventionsAttributeCiCdPipelineAttempt, int64(a.attempt))
+	}
+	rootSpan.Attributes().PutInt(conventionsAttributeCiCdPipelineAttempt, int64(attempt))
+}
+
+// Retried pipelines finish again, the attempt of a redelivered pipeline event is determined by its finished time
+func (p *glPipelineEvent) attempt() int {
+	if a, ok := p.attempts.get(p.Pipeline.FinishedAt); ok {
+		return a.attempt
+	}
+	return p.attempts.count + 1
+}
+
+// With stable trace ids all attempts of a pipeline share the trace. Jobs which finished before the previous attempt
+// (e.g. the successful jobs of a retried pipeline) are part of the trace already and aren't exported again.
+func (p *glPipelineEvent) isPreviousAttemptJob(j Job) bool {
+	if !p.stableTraceId {
+		return false
+	}
+	attempt := p.attempt()
+	previous := ""
+	for _, a := range p.attempts.latest {
+		if a.attempt < attempt {
+			previous = a.finishedAt
+		}
+	}
+	if previous == "" {
+		return false
+	}
+	previousFinishedAt, err := parseGitlabTime(previous)
+	if err != nil {
+		return false
+	}
+	finishedAt, err := parseGitlabTime(j.FinishedAt)
+	if err != nil {
+		return false
+	}
+	return finishedAt <= previousFinishedAt
+}
 
+// Child pipelines (source parent_pipeline) and multi-project pipelines (source pipeline) are triggered by a bridge job of their upstream pipeline
+func (p *glPipelineEvent) isDownstream() bool {
+	return (p.Pipeline.Source == "parent_pipeline" || p.Pipeline.Source == "pipeline") && p.ParentPipeline.Id != 0
+}
+
+// Upstream and downstream pipelines are exported as separate traces because their trace ids depend on their own finished time.
+// The pipeline which finishes last links to the other one.
+func addPipelineLink(span ptrace.Span, l pipelineLink, linkType string) {
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(l.traceId)
+	link.SetSpanID(l.spanId)
+	link.Attributes().PutStr(conventionsAttributeCidCPipelineRunId, strconv.Itoa(l.pipelineId))
+	link.Attributes().PutStr(conventionsAttributeCiCdPipelineLinkType, linkType)
+}
+
+// A finished job of the pipeline trace, the resource sets the attributes of the job span
+type jobSpan struct {
+	job   Job
+	res   spanResource
+	event *glJobEvent
+}
+
+// Finished jobs of the pipeline event and job events of jobs which aren't part of the pipeline event anymore (e.g. previous attempts of retried jobs)
+func (p *glPipelineEvent) finishedJobs() []jobSpan {
+	jobs := make([]jobSpan, 0, len(p.Jobs)+len(p.jobEvents))
+	jobIds := make(map[int]struct{}, len(p.Jobs))
 	for _, j := range p.Jobs {
-		if j.FinishedAt != "" {
+		jobIds[j.Id] = struct{}{}
+		if j.FinishedAt != "" && !p.isPreviousAttemptJob(j) {
 			jobUrl := fmt.Sprintf("%s/jobs/%s", p.Project.Url, strconv.Itoa(j.Id))
-			jobName := fmt.Sprintf("Job: %s - %s - Stage: %s", j.Name, strconv.Itoa(j.Id), j.Stage)
 			j.setDetails(jobUrl)
+			jobs = append(jobs, jobSpan{job: j, res: j, event: p.jobEvents[j.Id]})
+		}
+	}
 
-			startedAt, err := parseGitlabTime(j.StartedAt)
-			if err != nil {
-				return nil, err
-			}
-			finishedAt, err := parseGitlabTime(j.FinishedAt)
-			if err != nil {
-				return nil, err
-			}
-			createSpan(rs, traceId, getRandomSpanId(), rootSpanId, jobName, startedAt, finishedAt, j)
+	for _, e := range p.jobEvents {
+		if _, ok := jobIds[e.Id]; !ok && e.FinishedAt != "" && !p.isPreviousAttemptJob(e.job()) {
+			//The stored job events are shared, therefore the details are set on a copy
+			e := *e
+			e.setDetails(p.Project.Url, p.Pipeline.Url)
+			jobs = append(jobs, jobSpan{job: e.job(), res: &e})
 		}
 	}
-	return &trace, nil
+	return jobs
+}
+
+// The stage span covers all finished jobs of the stage: it starts with the earliest job and ends with the latest job.
+// The status of the stage span is the worst status of its jobs.
+func (p *glPipelineEvent) createStageSpans(rs ptrace.ResourceSpans, traceId [16]byte, rootSpanId [8]byte, jobs []jobSpan) (map[string][8]byte, error) {
+	stages := make([]*stage, 0)
+	byName := make(map[string]*stage)
+	for _, js := range jobs {
+		s, ok := byName[js.job.Stage]
+		if !ok {
+			s = &stage{name: js.job.Stage, pipelineId: p.Pipeline.Id, jobPhases: p.jobPhases}
+			byName[js.job.Stage] = s
+			stages = append(stages, s)
+		}
+		err := s.add(js.job)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	spanIds := make(map[string][8]byte, len(stages))
+	for _, s := range stages {
+		spanId, err := p.stageSpanId(s.name)
+		if err != nil {
+			return nil, err
+		}
+		createSpan(rs, traceId, spanId, rootSpanId, fmt.Sprintf("Stage: %s", s.name), s.startedAt, s.finishedAt, s)
+		spanIds[s.name] = spanId
+	}
+	return spanIds, nil
+}
+
+// The span ids of the child spans are based on the same hash as the trace id, so that events which are exported after
+// the pipeline trace (e.g. job events) can be attached to their parent span. Stages are part of every attempt, their
+// span ids depend on the finished time of the attempt like the root span.
+func (p *glPipelineEvent) stageSpanId(stage string) ([8]byte, error) {
+	return getStageSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.Pipeline.FinishedAt, stage)
+}
+
+func (p *glPipelineEvent) jobSpanId(jobId int) ([8]byte, error) {
+	return getJobSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.hashTime(), strconv.Itoa(jobId))
+}
+
+func (p *glPipelineEvent) deploymentSpanId(deploymentId int) ([8]byte, error) {
+	return getDeploymentSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.hashTime(), strconv.Itoa(deploymentId))
+}
+
+type stage struct {
+	name       string
+	pipelineId int
+	status     string
+	startedAt  pcommon.Timestamp
+	finishedAt pcommon.Timestamp
+	jobPhases  bool
+}
+
+// Jobs which never started (e.g. skipped jobs) only extend the end of the stage
+func (s *stage) add(j Job) error {
+	startedAt, err := parseGitlabTime(j.spanStartedAt(s.jobPhases))
+	if err != nil {
+		return err
+	}
+	finishedAt, err := parseGitlabTime(j.FinishedAt)
+	if err != nil {
+		return err
+	}
+	if startedAt == 0 {
+		startedAt = finishedAt
+	}
+
+	if s.startedAt == 0 || startedAt < s.startedAt {
+		s.startedAt = startedAt
+	}
+	if finishedAt > s.finishedAt {
+		s.finishedAt = finishedAt
+	}
+	if statusSeverity(j.Status) > statusSeverity(s.status) {
+		s.status = j.Status
+	}
+	return nil
+}
+
+// Higher values are worse
+func statusSeverity(status string) int {
+	switch status {
+	case "failed":
+		return 4
+	case "canceled":
+		return 3
+	case "success":
+		return 2
+	case "skipped":
+		return 1
+	}
+	return 0
+}
+
+func (s *stage) setAttributes(span ptrace.Span) {
+	attrs := span.Attributes()
+	attrs.PutStr(conventionsAttributeCiCdPipelineTaskType, getTaskType(s.name))
+	attrs.PutStr(conventionsAttributeCiCdJobStage, s.name)
+	attrs.PutStr(conventionsAttributeCidCPipelineRunId, strconv.Itoa(s.pipelineId))
+	setSpanStatus(span, s.status)
+}
+
+// The deployment of a job is a child span of the job span
+func (p *glPipelineEvent) createDeploymentSpan(rs ptrace.ResourceSpans, traceId [16]byte, jobSpanId [8]byte, jobId int) error {
+	d, ok := p.deployments[jobId]
+	if !ok || !d.isFinished() {
+		return nil
+	}
+	spanId, err := p.deploymentSpanId(d.Id)
+	if err != nil {
+		return err
+	}
+	_, err = d.createSpan(rs, traceId, spanId, jobSpanId)
+	return err
+}
+
+func createJobSpan(rs ptrace.ResourceSpans, traceId [16]byte, spanId [8]byte, parentSpanId [8]byte, j Job, glRes spanResource, phases bool) (ptrace.Span, error) {
+	jobName := fmt.Sprintf("Job: %s - %s - Stage: %s", j.Name, strconv.Itoa(j.Id), j.Stage)
+
+	startedAt, err := parseGitlabTime(j.spanStartedAt(phases))
+	if err != nil {
+		return ptrace.Span{}, err
+	}
+	finishedAt, err := parseGitlabTime(j.FinishedAt)
+	if err != nil {
+		return ptrace.Span{}, err
+	}
+	span := createSpan(rs, traceId, spanId, parentSpanId, jobName, startedAt, finishedAt, glRes)
+	if phases {
+		err = createJobPhaseSpans(rs, traceId, spanId, j)
+		if err != nil {
+			return ptrace.Span{}, err
+		}
+	}
+	return span, nil
+}
+
+// The queued span covers the time the job waited for a runner, the execution span the time the job ran on the runner.
+// Jobs which never started (e.g. jobs canceled while pending) only have a queued span.
+func createJobPhaseSpans(rs ptrace.ResourceSpans, traceId [16]byte, jobSpanId [8]byte, j Job) error {
+	createdAt, err := parseGitlabTime(j.CreatedAt)
+	if err != nil {
+		return err
+	}
+	startedAt, err := parseGitlabTime(j.StartedAt)
+	if err != nil {
+		return err
+	}
+	finishedAt, err := parseGitlabTime(j.FinishedAt)
+	if err != nil {
+		return err
+	}
+
+	queuedUntil := startedAt
+	if startedAt == 0 {
+		queuedUntil = finishedAt
+	}
+	if createdAt != 0 {
+		queued := jobPhase{name: jobPhaseQueued, job: j}
+		createSpan(rs, traceId, getJobPhaseSpanId(jobSpanId, jobPhaseQueued), jobSpanId, fmt.Sprintf("Queued: %s", j.Name), createdAt, queuedUntil, queued)
+	}
+	if startedAt != 0 {
+		execution := jobPhase{name: jobPhaseExecution, job: j}
+		createSpan(rs, traceId, getJobPhaseSpanId(jobSpanId, jobPhaseExecution), jobSpanId, fmt.Sprintf("Execution: %s", j.Name), startedAt, finishedAt, execution)
+	}
+	return nil
+}
+
+const (
+	jobPhaseQueued    = "queued"
+	jobPhaseExecution = "execution"
+)
+
+// Phase of a job span, the execution has the status of the job
+type jobPhase struct {
+	name string
+	job  Job
+}
+
+func (jp jobPhase) setAttributes(s ptrace.Span) {
+	attrs := s.Attributes()
+	attrs.PutStr(conventionsAttributeCiCdJobPhase, jp.name)
+	attrs.PutStr(conventionsAttributeCiCdTaskRunId, strconv.Itoa(jp.job.Id))
+	attrs.PutStr(conventionsAttributeCiCdJobName, jp.job.Name)
+	if jp.name == jobPhaseQueued {
+		attrs.PutStr(conventionsAttributeCiCdJobQueuedDuration, strconv.Itoa(int(jp.job.queuedDuration())))
+		s.Status().SetCode(ptrace.StatusCodeOk)
+		return
+	}
+	setSpanStatus(s, jp.job.Status)
+}
+
+// With job phases the job span starts with the creation of the job, jobs without creation time start with their execution
+func (j Job) spanStartedAt(phases bool) string {
+	if phases && j.CreatedAt != "" {
+		return j.CreatedAt
+	}
+	return j.StartedAt
+}
+
+// Seconds between the creation of the job and the start of its execution, like the queued span of the job. The queued
+// duration reported by Gitlab is often missing for finished jobs, it is only used without creation or start time.
+func (j Job) queuedDuration() float64 {
+	createdAt, err := parseGitlabTime(j.CreatedAt)
+	if err != nil || createdAt == 0 {
+		return j.QueuedDuration
+	}
+	startedAt, err := parseGitlabTime(j.StartedAt)
+	if err != nil || startedAt == 0 {
+		return j.QueuedDuration
+	}
+	return startedAt.AsTime().Sub(createdAt.AsTime()).Seconds()
 }
 
 // CICD Pipeline semconv: https://opentelemetry.io/docs/specs/semconv/attributes-registry/cicd/#cicd-pipeline-attributes
 func (p glPipelineEvent) setAttributes(s ptrace.Span) {
+	p.putAttributes(s.Attributes())
+	setSpanStatus(s, p.Pipeline.Status)
+}
+
+// The attributes are shared by spans and log records
+func (p glPipelineEvent) putAttributes(attrs pcommon.Map) {
 	vc := len(p.Pipeline.Variables)
-	s.Attributes().EnsureCapacity(12 + vc)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineUrl, p.Pipeline.Url)
-	s.Attributes().PutStr(conventionsAttributeCidCPipelineRunId, strconv.Itoa(p.Pipeline.Id))
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineDuration, strconv.Itoa(p.Pipeline.Duration))
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineQueuedDuration, strconv.Itoa(p.Pipeline.QueuedDuration))
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineUser, p.User.Name)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineUsername, p.User.Username)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineUserEmail, p.User.Email)
-
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineCommitMessage, p.Commit.Message)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineCommitTitle, p.Commit.Title)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineCommitTimestamp, p.Commit.Timestamp)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineCommitUrl, p.Commit.URL)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineCommitAuthorEmail, p.Commit.Author.Email)
+	attrs.EnsureCapacity(12 + vc)
+	attrs.PutStr(conventionsAttributeCiCdPipelineUrl, p.Pipeline.Url)
+	attrs.PutStr(conventionsAttributeCidCPipelineRunId, strconv.Itoa(p.Pipeline.Id))
+	attrs.PutStr(conventionsAttributeCiCdPipelineDuration, strconv.Itoa(p.Pipeline.Duration))
+	attrs.PutStr(conventionsAttributeCiCdPipelineQueuedDuration, strconv.Itoa(p.Pipeline.QueuedDuration))
+	attrs.PutStr(conventionsAttributeCiCdPipelineUser, p.User.Name)
+	attrs.PutStr(conventionsAttributeCiCdPipelineUsername, p.User.Username)
+	attrs.PutStr(conventionsAttributeCiCdPipelineUserEmail, p.User.Email)
+
+	attrs.PutStr(conventionsAttributeCiCdPipelineCommitMessage, p.Commit.Message)
+	attrs.PutStr(conventionsAttributeCiCdPipelineCommitTitle, p.Commit.Title)
+	attrs.PutStr(conventionsAttributeCiCdPipelineCommitTimestamp, p.Commit.Timestamp)
+	attrs.PutStr(conventionsAttributeCiCdPipelineCommitUrl, p.Commit.URL)
+	attrs.PutStr(conventionsAttributeCiCdPipelineCommitAuthorEmail, p.Commit.Author.Email)
 
 	for _, v := range p.Pipeline.Variables {
-		s.Attributes().PutStr(fmt.Sprintf("%s.%s", conventionsAttributeCiCdPipelineVariable, v.Key), v.Value)
+		attrs.PutStr(fmt.Sprintf("%s.%s", conventionsAttributeCiCdPipelineVariable, v.Key), v.Value)
 	}
 
-	if p.Pipeline.Source == "parent_pipeline" {
-		s.Attributes().PutStr(conventionsAttributeCiCdParentPipelineId, strconv.Itoa(p.ParentPipeline.Id))
-		parentPipelineUrl := fmt.Sprintf("%s/pipelines/%s", p.ParentPipeline.Project.Url, strconv.Itoa(p.ParentPipeline.Id))
-		s.Attributes().PutStr(conventionsAttributeCiCdParentPipelineUrl, parentPipelineUrl)
+	if p.MergeRequest.Iid != 0 {
+		p.MergeRequest.putAttributes(attrs)
 	}
 
-	setSpanStatus(s, p.Pipeline.Status)
+	if p.isDownstream() {
+		attrs.PutStr(conventionsAttributeCiCdParentPipelineId, strconv.Itoa(p.ParentPipeline.Id))
+		parentPipelineUrl := fmt.Sprintf("%s/pipelines/%s", p.ParentPipeline.Project.Url, strconv.Itoa(p.ParentPipeline.Id))
+		attrs.PutStr(conventionsAttributeCiCdParentPipelineUrl, parentPipelineUrl)
+	}
 }
 
 func (j Job) setAttributes(s ptrace.Span) {
-	stage := strings.ToLower(j.Stage)
+	j.putAttributes(s.Attributes())
+	setSpanStatus(s, j.Status)
+}
+
+func (j Job) putAttributes(attrs pcommon.Map) {
+	rtc := len(j.Runner.Tags)
+	attrs.EnsureCapacity(11 + rtc)
+	attrs.PutStr(conventionsAttributeCiCdTaskRunId, strconv.Itoa(j.Id))
+	attrs.PutStr(conventionsAttributeCiCdTaskRunUrl, j.Url)
+	attrs.PutStr(conventionsAttributeCiCdPipelineTaskType, getTaskType(j.Stage))
+	attrs.PutStr(conventionsAttributeCiCdJobEnvironment, j.Environment.Name)
+	attrs.PutStr(conventionsAttributeCiCdJobRunnerId, strconv.Itoa(j.Runner.Id))
+	attrs.PutStr(conventionsAttributeCiCdJobRunnerDescription, j.Runner.Description)
+	attrs.PutStr(conventionsAttributeCiCdJobRunnerIsActive, strconv.FormatBool(j.Runner.IsActive))
+	attrs.PutStr(conventionsAttributeCiCdJobRunnerIsShared, strconv.FormatBool(j.Runner.IsShared))
+	attrs.PutStr(conventionsAttributeCiCdJobDuration, strconv.Itoa(int(j.Duration)))
+	attrs.PutStr(conventionsAttributeCiCdJobQueuedDuration, strconv.Itoa(int(j.queuedDuration())))
+	attrs.PutStr(conventionsAttributeCiCdJobName, j.Name)
+
+	for _, t := range j.Runner.Tags {
+		attrs.PutStr(conventionsAttributeCiCdJobRunnerTag, t)
+	}
+}
+
+// Job events can only be exported on their own if the trace of their pipeline is already known (see pipelineStore),
+// otherwise they are exported as part of the pipeline trace.
+func (e *glJobEvent) newTrace() (*ptrace.Traces, error) {
+	if e.traceId == [16]byte{} || e.spanId == [8]byte{} || e.parentSpanId == [8]byte{} {
+		return nil, errors.New("the trace of the pipeline is unknown")
+	}
+
+	trace := ptrace.NewTraces()
+	rs := trace.ResourceSpans().AppendEmpty()
+	setProjectResource(rs.Resource(), e.Project)
+	rs.Resource().Attributes().PutStr(conventionsAttributeSpanSource, fmt.Sprintf("%s-receiver", typeStr.String()))
+
+	_, err := createJobSpan(rs, e.traceId, e.spanId, e.parentSpanId, e.job(), e, e.jobPhases)
+	if err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+func (e *glJobEvent) setAttributes(s ptrace.Span) {
+	e.putAttributes(s.Attributes())
+	setSpanStatus(s, e.Status)
+}
+
+func (e *glJobEvent) putAttributes(attrs pcommon.Map) {
+	e.job().putAttributes(attrs)
+	e.putJobEventAttributes(attrs)
+	attrs.PutStr(conventionsAttributeCidCPipelineRunId, strconv.Itoa(e.PipelineId))
+	attrs.PutStr(conventionsAttributeCiCdPipelineUrl, e.PipelineUrl)
+}
+
+// Attributes which are only part of the job event, the queued duration is part of the job attributes
+func (e *glJobEvent) putJobEventAttributes(attrs pcommon.Map) {
+	attrs.PutStr(conventionsAttributeCiCdJobRetriesCount, strconv.Itoa(e.RetriesCount))
+	if e.FailureReason != "" {
+		attrs.PutStr(conventionsAttributeCiCdJobFailureReason, e.FailureReason)
+	}
+}
+
+// The job event represented as job of a pipeline event
+func (e *glJobEvent) job() Job {
+	return Job{
+		Id:             e.Id,
+		Name:           e.Name,
+		Status:         e.Status,
+		Stage:          e.Stage,
+		CreatedAt:      e.CreatedAt,
+		StartedAt:      e.StartedAt,
+		FinishedAt:     e.FinishedAt,
+		Url:            e.JobUrl,
+		ProjectPath:    e.Project.Path,
+		Runner:         e.Runner,
+		Environment:    e.Environment,
+		Duration:       e.Duration,
+		QueuedDuration: e.QueuedDuration,
+	}
+}
+
+// Set additional job event fields/details which are not getting captured automatically by decoding the gitlab event webhook
+func (e *glJobEvent) setDetails(projectUrl string, pipelineUrl string) {
+	e.JobUrl = fmt.Sprintf("%s/jobs/%s", projectUrl, strconv.Itoa(e.Id))
+	e.PipelineUrl = pipelineUrl
+}
+
+// Like job events, deployments can only be exported on their own if the trace of their pipeline is already known
+func (d *glDeploymentEvent) newTrace() (*ptrace.Traces, error) {
+	if d.traceId == [16]byte{} || d.spanId == [8]byte{} || d.parentSpanId == [8]byte{} {
+		return nil, errors.New("the trace of the pipeline is unknown")
+	}
+
+	trace := ptrace.NewTraces()
+	rs := trace.ResourceSpans().AppendEmpty()
+	setProjectResource(rs.Resource(), d.Project)
+	rs.Resource().Attributes().PutStr(conventionsAttributeSpanSource, fmt.Sprintf("%s-receiver", typeStr.String()))
+
+	_, err := d.createSpan(rs, d.traceId, d.spanId, d.parentSpanId)
+	if err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// Deployment events only carry the time of the last status change. The span starts with the status change to running,
+// if the running event wasn't received the span has no duration.
+func (d *glDeploymentEvent) createSpan(rs ptrace.ResourceSpans, traceId [16]byte, spanId [8]byte, parentSpanId [8]byte) (ptrace.Span, error) {
+	name := fmt.Sprintf("Deployment: %s - %s", d.Environment, strconv.Itoa(d.Id))
+
+	finishedAt, err := parseGitlabTime(d.StatusChangedAt)
+	if err != nil {
+		return ptrace.Span{}, err
+	}
+	startedAt := finishedAt
+	if d.startedAt != "" {
+		startedAt, err = parseGitlabTime(d.startedAt)
+		if err != nil {
+			return ptrace.Span{}, err
+		}
+	}
+	return createSpan(rs, traceId, spanId, parentSpanId, name, startedAt, finishedAt, d), nil
+}
+
+func (d *glDeploymentEvent) setAttributes(s ptrace.Span) {
+	d.putAttributes(s.Attributes())
+	setSpanStatus(s, d.Status)
+}
+
+func (d *glDeploymentEvent) putAttributes(attrs pcommon.Map) {
+	attrs.EnsureCapacity(10)
+	attrs.PutStr(conventionsAttributeDeploymentId, strconv.Itoa(d.Id))
+	attrs.PutStr(conventionsAttributeDeploymentStatus, d.Status)
+	attrs.PutStr(conventionsAttributeDeploymentEnvironmentName, d.Environment)
+	attrs.PutStr(conventionsAttributeDeploymentEnvironmentTier, d.EnvironmentTier)
+	attrs.PutStr(conventionsAttributeDeploymentEnvironmentUrl, d.EnvironmentExternalUrl)
+	attrs.PutStr(conventionsAttributeDeploymentCommitTitle, d.CommitTitle)
+	attrs.PutStr(conventionsAttributeDeploymentCommitUrl, d.CommitUrl)
+	attrs.PutStr(conventionsAttributeDeploymentUser, d.User.Username)
+	attrs.PutStr(conventionsAttributeCiCdTaskRunId, strconv.Itoa(d.DeployableId))
+	attrs.PutStr(conventionsAttributeCiCdTaskRunUrl, d.DeployableUrl)
+}
+
+func (d *glDeploymentEvent) isFinished() bool {
+	switch d.Status {
+	case "success", "failed", "canceled":
+		return true
+	}
+	return false
+}
+
+// In Gitlab a stage can be seen as task type -> well known values: build,deploy,test
+func getTaskType(stage string) string {
+	stage = strings.ToLower(stage)
 	switch {
 	case strings.Contains(stage, "build"):
 		stage = "build"
@@ -123,29 +674,15 @@ func (j Job) setAttributes(s ptrace.Span) {
 	case strings.Contains(stage, "deploy"):
 		stage = "deploy"
 	}
-
-	rtc := len(j.Runner.Tags)
-	s.Attributes().EnsureCapacity(10 + rtc)
-	s.Attributes().PutStr(conventionsAttributeCiCdTaskRunId, strconv.Itoa(j.Id))
-	s.Attributes().PutStr(conventionsAttributeCiCdTaskRunUrl, j.Url)
-	s.Attributes().PutStr(conventionsAttributeCiCdPipelineTaskType, stage)
-	s.Attributes().PutStr(conventionsAttributeCiCdJobEnvironment, j.Environment.Name)
-	s.Attributes().PutStr(conventionsAttributeCiCdJobRunnerId, strconv.Itoa(j.Runner.Id))
-	s.Attributes().PutStr(conventionsAttributeCiCdJobRunnerDescription, j.Runner.Description)
-	s.Attributes().PutStr(conventionsAttributeCiCdJobRunnerIsActive, strconv.FormatBool(j.Runner.IsActive))
-	s.Attributes().PutStr(conventionsAttributeCiCdJobRunnerIsShared, strconv.FormatBool(j.Runner.IsShared))
-	s.Attributes().PutStr(conventionsAttributeCiCdJobDuration, strconv.Itoa(int(j.Duration)))
-	s.Attributes().PutStr(conventionsAttributeCiCdJobName, j.Name)
-
-	for _, t := range j.Runner.Tags {
-		s.Attributes().PutStr(conventionsAttributeCiCdJobRunnerTag, t)
-	}
-
-	setSpanStatus(s, j.Status)
+	return stage
 }
 
-func (j Job) newTrace() (*ptrace.Traces, error) {
-	return nil, nil
+func setProjectResource(res pcommon.Resource, project Project) {
+	res.Attributes().PutStr(conventions.AttributeServiceName, project.Path)
+	res.Attributes().PutStr(conventionsAttributeCiCdRepositoryName, project.Name)
+	res.Attributes().PutStr(conventionsAttributeCiCdRepositoryUrl, project.Url)
+	res.Attributes().PutStr(conventionsAttributeCiCdRepositoryPath, project.Path)
+	res.Attributes().PutStr(conventionsAttributeCiCdRepositoryId, strconv.Itoa(project.Id))
 }
 
 func setSpanStatus(s ptrace.Span, status string) {
@@ -178,12 +715,17 @@ func parseGitlabTime(t string) (pcommon.Timestamp, error) {
 		return pcommon.NewTimestampFromTime(pt), nil
 	}
 
+	pt, err = time.Parse(gitlabHookTimeFormat, t)
+	if err == nil {
+		return pcommon.NewTimestampFromTime(pt), nil
+	}
+
 	return 0, err
 }
 
-func decode[T any](r *http.Request) (T, error) {
+func decode[T any](body []byte) (T, error) {
 	var v T
-	err := json.NewDecoder(r.Body).Decode(&v)
+	err := json.Unmarshal(body, &v)
 	if err != nil {
 		return v, fmt.Errorf("decode json: %w", err)
 	}
@@ -3,12 +3,10 @@ package gitlabreceiver
 import (
 	"encoding/hex"
 	"fmt"
-	"io"
-	"net/http"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
@@ -385,11 +383,7 @@ func TestSetSpanStatus(t *testing.T) {
 }
 
 func TestDecode(t *testing.T) {
-	req := &http.Request{
-		Body: io.NopCloser(strings.NewReader(gitlabPipelineEvent)),
-	}
-
-	got, err := decode[glPipelineEvent](req)
+	got, err := decode[glPipelineEvent]([]byte(gitlabPipelineEvent))
 	if err != nil {
 		t.Fatalf("expected no error, but got: %v", err)
 	}
@@ -430,4 +424,288 @@ func TestParseGitlabTime(t *testing.T) {
 	}
 	expectedTimestamp := pcommon.Timestamp(1704112215000000000)
 	assert.Equal(t, validTime, expectedTimestamp)
+
+	deploymentTime, err := parseGitlabTime("2024-01-01 13:30:15 +0100")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTimestamp, deploymentTime)
+}
+
+func TestPipelineEventNewTraceWithJobEvents(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.jobEvents = map[int]*glJobEvent{
+		11: {Id: 11, PipelineId: 1, Name: "test", Stage: "test", Status: "failed", FailureReason: "script_failure", RetriesCount: 1, StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime},
+		10: {Id: 10, PipelineId: 1, Name: "test", Stage: "test", Status: "failed", FailureReason: "runner_system_failure", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime},
+	}
+
+	traces, err := p.newTrace()
+	assert.NoError(t, err)
+
+	spans := collectSpans(*traces)
+	assert.Len(t, spans, 4, "root span, 2 finished jobs and 1 previous job attempt expected")
+
+	traceId, rootSpanId, err := p.traceContext()
+	assert.NoError(t, err)
+	for _, s := range spans {
+		assert.Equal(t, pcommon.TraceID(traceId), s.TraceID())
+	}
+
+	job := spans["Job: test - 11 - Stage: test"]
+	assert.Equal(t, pcommon.SpanID(rootSpanId), job.ParentSpanID())
+	failureReason, _ := job.Attributes().Get(conventionsAttributeCiCdJobFailureReason)
+	assert.Equal(t, "script_failure", failureReason.Str())
+	retries, _ := job.Attributes().Get(conventionsAttributeCiCdJobRetriesCount)
+	assert.Equal(t, "1", retries.Str())
+
+	previousAttempt := spans["Job: test - 10 - Stage: test"]
+	assert.Equal(t, pcommon.SpanID(rootSpanId), previousAttempt.ParentSpanID())
+	failureReason, _ = previousAttempt.Attributes().Get(conventionsAttributeCiCdJobFailureReason)
+	assert.Equal(t, "runner_system_failure", failureReason.Str())
+	url, _ := previousAttempt.Attributes().Get(conventionsAttributeCiCdTaskRunUrl)
+	assert.Equal(t, "https://gitlab.com/group/project/jobs/10", url.Str())
+}
+
+func TestPipelineEventNewTraceWithDeployments(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.deployments = map[int]*glDeploymentEvent{
+		12: newDeploymentEvent(5, 12, "success"),
+		//Deployments which aren't finished are ignored
+		11: newDeploymentEvent(4, 11, "running"),
+	}
+
+	traces, err := p.newTrace()
+	require.NoError(t, err)
+
+	spans := collectSpans(*traces)
+	assert.Len(t, spans, 4, "root span, 2 finished jobs and 1 deployment expected")
+
+	deployment := spans["Deployment: production - 5"]
+	assert.Equal(t, spans["Job: build - 12 - Stage: build"].SpanID(), deployment.ParentSpanID())
+	assert.Equal(t, getParsedGitlabTime(gitlabStartTime), deployment.StartTimestamp())
+	assert.Equal(t, getParsedGitlabTime(gitlabEndTime), deployment.EndTimestamp())
+	assert.Equal(t, ptrace.StatusCodeOk, deployment.Status().Code())
+	env, _ := deployment.Attributes().Get(conventionsAttributeDeploymentEnvironmentName)
+	assert.Equal(t, "production", env.Str())
+	status, _ := deployment.Attributes().Get(conventionsAttributeDeploymentStatus)
+	assert.Equal(t, "success", status.Str())
+	jobId, _ := deployment.Attributes().Get(conventionsAttributeCiCdTaskRunId)
+	assert.Equal(t, "12", jobId.Str())
+}
+
+func TestPipelineEventNewTraceWithStageSpans(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.stageSpans = true
+	p.Jobs = append(p.Jobs, Job{Id: 14, Name: "lint", Stage: "test", Status: "success", StartedAt: "2024-01-01 12:20:15 UTC", FinishedAt: "2024-01-01 12:35:15 UTC"})
+
+	traces, err := p.newTrace()
+	require.NoError(t, err)
+
+	spans := collectSpans(*traces)
+	assert.Len(t, spans, 6, "root span, 2 stages and 3 finished jobs expected")
+	assert.NotContains(t, spans, "Stage: deploy", "stages without finished jobs are omitted")
+
+	_, rootSpanId, err := p.traceContext()
+	require.NoError(t, err)
+	stageSpanId, err := p.stageSpanId("test")
+	require.NoError(t, err)
+
+	testStage := spans["Stage: test"]
+	assert.Equal(t, pcommon.SpanID(stageSpanId), testStage.SpanID())
+	assert.Equal(t, pcommon.SpanID(rootSpanId), testStage.ParentSpanID())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:20:15 UTC"), testStage.StartTimestamp())
+	assert.Equal(t, getParsedGitlabTime(gitlabEndTime), testStage.EndTimestamp())
+	assert.Equal(t, ptrace.StatusCodeError, testStage.Status().Code(), "the worst job status defines the stage status")
+	assert.Equal(t, ptrace.StatusCodeOk, spans["Stage: build"].Status().Code())
+
+	assert.Equal(t, testStage.SpanID(), spans["Job: test - 11 - Stage: test"].ParentSpanID())
+	assert.Equal(t, testStage.SpanID(), spans["Job: lint - 14 - Stage: test"].ParentSpanID())
+	assert.Equal(t, spans["Stage: build"].SpanID(), spans["Job: build - 12 - Stage: build"].ParentSpanID())
+}
+
+func TestPipelineEventNewTraceWithJobPhases(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.jobPhases = true
+	p.stageSpans = true
+	p.Jobs[0].CreatedAt = "2024-01-01 12:25:15 UTC"
+	p.Jobs = append(p.Jobs, Job{Id: 14, Name: "lint", Stage: "test", Status: "canceled", CreatedAt: "2024-01-01 12:30:15 UTC", FinishedAt: "2024-01-01 12:35:15 UTC"})
+
+	traces, err := p.newTrace()
+	require.NoError(t, err)
+	spans := collectSpans(*traces)
+
+	//The job span starts with the creation of the job, jobs without creation time start with their execution
+	job := spans["Job: test - 11 - Stage: test"]
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:25:15 UTC"), job.StartTimestamp())
+	assert.Equal(t, getParsedGitlabTime(gitlabEndTime), job.EndTimestamp())
+	queuedDuration, _ := job.Attributes().Get(conventionsAttributeCiCdJobQueuedDuration)
+	assert.Equal(t, "300", queuedDuration.Str())
+	assert.Equal(t, getParsedGitlabTime(gitlabStartTime), spans["Job: build - 12 - Stage: build"].StartTimestamp())
+	assert.Equal(t, job.StartTimestamp(), spans["Stage: test"].StartTimestamp(), "the stage starts with the creation of its first job")
+
+	queued := spans["Queued: test"]
+	assert.Equal(t, job.SpanID(), queued.ParentSpanID())
+	assert.Equal(t, pcommon.SpanID(getJobPhaseSpanId(job.SpanID(), jobPhaseQueued)), queued.SpanID())
+	assert.Equal(t, job.StartTimestamp(), queued.StartTimestamp())
+	assert.Equal(t, getParsedGitlabTime(gitlabStartTime), queued.EndTimestamp())
+	assert.Equal(t, ptrace.StatusCodeOk, queued.Status().Code())
+	phase, _ := queued.Attributes().Get(conventionsAttributeCiCdJobPhase)
+	assert.Equal(t, jobPhaseQueued, phase.Str())
+
+	execution := spans["Execution: test"]
+	assert.Equal(t, job.SpanID(), execution.ParentSpanID())
+	assert.Equal(t, getParsedGitlabTime(gitlabStartTime), execution.StartTimestamp())
+	assert.Equal(t, job.EndTimestamp(), execution.EndTimestamp())
+	assert.Equal(t, ptrace.StatusCodeError, execution.Status().Code(), "the execution has the status of the job")
+
+	//Jobs which never started were queued until they finished
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:35:15 UTC"), spans["Queued: lint"].EndTimestamp())
+	assert.NotContains(t, spans, "Execution: lint")
+	assert.NotContains(t, spans, "Queued: build", "jobs without creation time have no queued span")
+}
+
+func TestJobQueuedDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      Job
+		expected float64
+	}{
+		{name: "calculated", job: Job{CreatedAt: "2024-01-01 12:25:15 UTC", StartedAt: gitlabStartTime}, expected: 300},
+		{name: "calculated and reported", job: Job{QueuedDuration: 1.5, CreatedAt: "2024-01-01 12:25:15 UTC", StartedAt: gitlabStartTime}, expected: 300},
+		{name: "reported", job: Job{QueuedDuration: 1.5, StartedAt: gitlabStartTime}, expected: 1.5},
+		{name: "not started", job: Job{CreatedAt: "2024-01-01 12:25:15 UTC"}},
+		{name: "unknown creation", job: Job{StartedAt: gitlabStartTime}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.job.queuedDuration())
+		})
+	}
+}
+
+func TestJobQueuedDurationOfJobEvents(t *testing.T) {
+	//Finished job events usually don't report the queued duration, it must not replace the calculated one
+	p := newFinishedPipelineEvent()
+	p.Jobs[0].CreatedAt = "2024-01-01 12:25:15 UTC"
+	p.jobEvents = map[int]*glJobEvent{11: {Id: 11, PipelineId: 1, Name: "test", Stage: "test", Status: "failed", FailureReason: "script_failure", CreatedAt: "2024-01-01 12:25:15 UTC", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime}}
+
+	trace, err := p.newTrace()
+	require.NoError(t, err)
+	attrs := collectSpans(*trace)["Job: test - 11 - Stage: test"].Attributes()
+	queuedDuration, _ := attrs.Get(conventionsAttributeCiCdJobQueuedDuration)
+	assert.Equal(t, "300", queuedDuration.Str())
+	failureReason, _ := attrs.Get(conventionsAttributeCiCdJobFailureReason)
+	assert.Equal(t, "script_failure", failureReason.Str())
+}
+
+func TestPipelineEventNewTraceDeterministicSpanIds(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	traces, err := p.newTrace()
+	require.NoError(t, err)
+	redelivered, err := p.newTrace()
+	require.NoError(t, err)
+
+	spans := collectSpans(*traces)
+	redeliveredSpans := collectSpans(*redelivered)
+	for name, s := range spans {
+		assert.Equal(t, s.SpanID(), redeliveredSpans[name].SpanID(), "redelivered webhooks must create the same span ids")
+	}
+
+	jobSpanId, err := getJobSpanId("abc123", "1", gitlabEndTime, "11")
+	require.NoError(t, err)
+	assert.Equal(t, pcommon.SpanID(jobSpanId), spans["Job: test - 11 - Stage: test"].SpanID())
+}
+
+func TestStatusSeverity(t *testing.T) {
+	assert.Greater(t, statusSeverity("failed"), statusSeverity("canceled"))
+	assert.Greater(t, statusSeverity("canceled"), statusSeverity("success"))
+	assert.Greater(t, statusSeverity("success"), statusSeverity("skipped"))
+	assert.Greater(t, statusSeverity("skipped"), statusSeverity("manual"))
+}
+
+func TestJobEventNewTrace(t *testing.T) {
+	e := &glJobEvent{Id: 11, PipelineId: 1, Name: "test", Stage: "test", Status: "failed", FailureReason: "script_failure", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime}
+
+	_, err := e.newTrace()
+	assert.Error(t, err, "job events without a pipeline trace context can't be exported")
+
+	e.traceId = generateExpectedTraceId("abc123", "1", gitlabEndTime)
+	e.parentSpanId = generateExpectedSpanId("abc123", "1", gitlabEndTime)
+	e.spanId = generateExpectedSpanId("abc123", "1", gitlabEndTime+"job:11")
+	traces, err := e.newTrace()
+	assert.NoError(t, err)
+
+	spans := collectSpans(*traces)
+	assert.Len(t, spans, 1)
+	job := spans["Job: test - 11 - Stage: test"]
+	assert.Equal(t, pcommon.TraceID(e.traceId), job.TraceID())
+	assert.Equal(t, pcommon.SpanID(e.spanId), job.SpanID())
+	assert.Equal(t, pcommon.SpanID(e.parentSpanId), job.ParentSpanID())
+	assert.Equal(t, ptrace.StatusCodeError, job.Status().Code())
+	pipelineId, _ := job.Attributes().Get(conventionsAttributeCidCPipelineRunId)
+	assert.Equal(t, "1", pipelineId.Str())
+
+	//Job events are split into the same phase spans as the jobs of the pipeline trace
+	e.jobPhases = true
+	e.CreatedAt = "2024-01-01 12:25:15 UTC"
+	traces, err = e.newTrace()
+	require.NoError(t, err)
+	spans = collectSpans(*traces)
+	assert.Len(t, spans, 3)
+	assert.Equal(t, pcommon.SpanID(getJobPhaseSpanId(e.spanId, jobPhaseExecution)), spans["Execution: test"].SpanID())
+}
+
+func newFinishedPipelineEvent() *glPipelineEvent {
+	return &glPipelineEvent{
+		Kind: "pipeline",
+		Pipeline: Pipeline{
+			Id:         1,
+			Status:     "failed",
+			Ref:        "main",
+			Url:        "https://gitlab.com/group/project/-/pipelines/1",
+			Sha:        "abc123",
+			CreatedAt:  gitlabStartTime,
+			FinishedAt: gitlabEndTime,
+		},
+		Jobs: []Job{
+			{Id: 11, Name: "test", Stage: "test", Status: "failed", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime},
+			{Id: 12, Name: "build", Stage: "build", Status: "success", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime},
+			{Id: 13, Name: "deploy", Stage: "deploy", Status: "manual"},
+		},
+		Project: Project{
+			Id:   42,
+			Name: "project",
+			Path: "group/project",
+			Url:  "https://gitlab.com/group/project",
+		},
+	}
+}
+
+func newDeploymentEvent(id int, deployableId int, status string) *glDeploymentEvent {
+	return &glDeploymentEvent{
+		Kind:            "deployment",
+		Id:              id,
+		Status:          status,
+		StatusChangedAt: "2024-01-01 13:40:15 +0100",
+		DeployableId:    deployableId,
+		DeployableUrl:   fmt.Sprintf("https://gitlab.com/group/project/-/jobs/%d", deployableId),
+		Environment:     "production",
+		EnvironmentTier: "production",
+		Project:         Project{Id: 42, Name: "project", Path: "group/project", Url: "https://gitlab.com/group/project"},
+		startedAt:       "2024-01-01 13:30:15 +0100",
+	}
+}
+
+// Spans of all resource and scope spans by span name
+func collectSpans(traces ptrace.Traces) map[string]ptrace.Span {
+	spans := make(map[string]ptrace.Span)
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		rs := traces.ResourceSpans().At(i)
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			for k := 0; k < ss.Spans().Len(); k++ {
+				spans[ss.Spans().At(k).Name()] = ss.Spans().At(k)
+			}
+		}
+	}
+	return spans
 }
@@ -0,0 +1,125 @@
+package gitlabreceiver
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// Every pipeline event is exported as log record, including the events of pending and running pipelines which aren't exported as traces.
+// Finished pipelines are correlated with the root span of their trace.
+func (p *glPipelineEvent) newLogs() (plog.Logs, error) {
+	ts, err := parseGitlabTime(firstNonEmpty(p.Pipeline.FinishedAt, p.Pipeline.CreatedAt))
+	if err != nil {
+		return plog.Logs{}, err
+	}
+
+	logs := plog.NewLogs()
+	lr := newLogRecord(logs, p.Project, ts, p.Pipeline.Status)
+	lr.Body().SetStr(fmt.Sprintf("Pipeline %d %s", p.Pipeline.Id, p.Pipeline.Status))
+	p.putAttributes(lr.Attributes())
+	lr.Attributes().PutStr(conventionsAttributeCiCdPipelineRef, p.Pipeline.Ref)
+	lr.Attributes().PutStr(conventionsAttributeCiCdPipelineStatus, p.Pipeline.Status)
+	lr.Attributes().PutStr(conventionsAttributeCiCdPipelineSource, p.Pipeline.Source)
+
+	if p.Pipeline.FinishedAt != "" && p.Pipeline.Status != "running" {
+		traceId, rootSpanId, err := p.traceContext()
+		if err == nil {
+			lr.SetTraceID(traceId)
+			lr.SetSpanID(rootSpanId)
+		}
+	}
+
+	return logs, nil
+}
+
+func (e *glJobEvent) newLogs() (plog.Logs, error) {
+	ts, err := parseGitlabTime(firstNonEmpty(e.FinishedAt, e.StartedAt, e.CreatedAt))
+	if err != nil {
+		return plog.Logs{}, err
+	}
+
+	logs := plog.NewLogs()
+	lr := newLogRecord(logs, e.Project, ts, e.Status)
+	lr.Body().SetStr(fmt.Sprintf("Job %s (%d) %s", e.Name, e.Id, e.Status))
+	e.putAttributes(lr.Attributes())
+	lr.Attributes().PutStr(conventionsAttributeCiCdJobStage, e.Stage)
+	lr.Attributes().PutStr(conventionsAttributeCiCdJobStatus, e.Status)
+
+	return logs, nil
+}
+
+// Every deployment event is exported as log record, including the status changes to created and running
+func (d *glDeploymentEvent) newLogs() (plog.Logs, error) {
+	ts, err := parseGitlabTime(d.StatusChangedAt)
+	if err != nil {
+		return plog.Logs{}, err
+	}
+
+	logs := plog.NewLogs()
+	lr := newLogRecord(logs, d.Project, ts, d.Status)
+	lr.Body().SetStr(fmt.Sprintf("Deployment %d to %s %s", d.Id, d.Environment, d.Status))
+	d.putAttributes(lr.Attributes())
+
+	return logs, nil
+}
+
+// Every merge request event is exported as log record, including the updates which aren't part of the merge request
+// trace. The log records carry the trace id of the merge request trace.
+func (mr *glMergeRequestEvent) newLogs() (plog.Logs, error) {
+	ts, err := mr.eventTime()
+	if err != nil {
+		return plog.Logs{}, err
+	}
+
+	logs := plog.NewLogs()
+	lr := newLogRecord(logs, mr.Project, ts, mr.MergeRequest.State)
+	lr.Body().SetStr(fmt.Sprintf("Merge Request !%d %s", mr.MergeRequest.Iid, mr.MergeRequest.Action))
+	mr.putAttributes(lr.Attributes())
+
+	traceId, err := getMergeRequestTraceId(strconv.Itoa(mr.Project.Id), strconv.Itoa(mr.MergeRequest.Iid))
+	if err == nil {
+		lr.SetTraceID(traceId)
+	}
+
+	return logs, nil
+}
+
+func newLogRecord(logs plog.Logs, project Project, ts pcommon.Timestamp, status string) plog.LogRecord {
+	rl := logs.ResourceLogs().AppendEmpty()
+	setProjectResource(rl.Resource(), project)
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(typeStr.String() + "receiver")
+
+	lr := sl.LogRecords().AppendEmpty()
+	lr.SetTimestamp(ts)
+	lr.SetObservedTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	severity := getSeverity(status)
+	lr.SetSeverityNumber(severity)
+	lr.SetSeverityText(severity.String())
+	return lr
+}
+
+// Failed pipelines and jobs are errors, canceled ones warnings. All other states are considered as regular state transitions.
+func getSeverity(status string) plog.SeverityNumber {
+	switch status {
+	case "failed":
+		return plog.SeverityNumberError
+	case "canceled":
+		return plog.SeverityNumberWarn
+	default:
+		return plog.SeverityNumberInfo
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" && v != "null" {
+			return v
+		}
+	}
+	return ""
+}
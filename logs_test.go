@@ -0,0 +1,133 @@
+package gitlabreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestPipelineEventNewLogs(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           string
+		finishedAt       string
+		expectedSeverity plog.SeverityNumber
+		expectedTime     pcommon.Timestamp
+		expectedTraceCtx bool
+	}{
+		{
+			name:             "running pipeline",
+			status:           "running",
+			expectedSeverity: plog.SeverityNumberInfo,
+			expectedTime:     getParsedGitlabTime(gitlabStartTime),
+		},
+		{
+			name:             "failed pipeline",
+			status:           "failed",
+			finishedAt:       gitlabEndTime,
+			expectedSeverity: plog.SeverityNumberError,
+			expectedTime:     getParsedGitlabTime(gitlabEndTime),
+			expectedTraceCtx: true,
+		},
+		{
+			name:             "canceled pipeline",
+			status:           "canceled",
+			finishedAt:       gitlabEndTime,
+			expectedSeverity: plog.SeverityNumberWarn,
+			expectedTime:     getParsedGitlabTime(gitlabEndTime),
+			expectedTraceCtx: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newFinishedPipelineEvent()
+			p.Pipeline.Status = tc.status
+			p.Pipeline.FinishedAt = tc.finishedAt
+
+			logs, err := p.newLogs()
+			require.NoError(t, err)
+			require.Equal(t, 1, logs.LogRecordCount())
+
+			rl := logs.ResourceLogs().At(0)
+			serviceName, _ := rl.Resource().Attributes().Get("service.name")
+			assert.Equal(t, "group/project", serviceName.Str())
+
+			lr := rl.ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tc.expectedSeverity, lr.SeverityNumber())
+			assert.Equal(t, tc.expectedTime, lr.Timestamp())
+			assert.Equal(t, "Pipeline 1 "+tc.status, lr.Body().Str())
+			status, _ := lr.Attributes().Get(conventionsAttributeCiCdPipelineStatus)
+			assert.Equal(t, tc.status, status.Str())
+			pipelineId, _ := lr.Attributes().Get(conventionsAttributeCidCPipelineRunId)
+			assert.Equal(t, "1", pipelineId.Str())
+
+			if tc.expectedTraceCtx {
+				traceId, rootSpanId, err := p.traceContext()
+				require.NoError(t, err)
+				assert.Equal(t, pcommon.TraceID(traceId), lr.TraceID())
+				assert.Equal(t, pcommon.SpanID(rootSpanId), lr.SpanID())
+			} else {
+				assert.True(t, lr.TraceID().IsEmpty())
+			}
+		})
+	}
+}
+
+func TestJobEventNewLogs(t *testing.T) {
+	e := &glJobEvent{Id: 11, PipelineId: 1, Name: "test", Stage: "test", Status: "failed", FailureReason: "script_failure", CreatedAt: gitlabStartTime, FinishedAt: gitlabEndTime}
+
+	logs, err := e.newLogs()
+	require.NoError(t, err)
+	require.Equal(t, 1, logs.LogRecordCount())
+
+	lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, plog.SeverityNumberError, lr.SeverityNumber())
+	assert.Equal(t, getParsedGitlabTime(gitlabEndTime), lr.Timestamp())
+	assert.Equal(t, "Job test (11) failed", lr.Body().Str())
+	failureReason, _ := lr.Attributes().Get(conventionsAttributeCiCdJobFailureReason)
+	assert.Equal(t, "script_failure", failureReason.Str())
+	stage, _ := lr.Attributes().Get(conventionsAttributeCiCdJobStage)
+	assert.Equal(t, "test", stage.Str())
+}
+
+func TestDeploymentEventNewLogs(t *testing.T) {
+	d := newDeploymentEvent(5, 13, "failed")
+
+	logs, err := d.newLogs()
+	require.NoError(t, err)
+	require.Equal(t, 1, logs.LogRecordCount())
+
+	lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, plog.SeverityNumberError, lr.SeverityNumber())
+	assert.Equal(t, getParsedGitlabTime(d.StatusChangedAt), lr.Timestamp())
+	assert.Equal(t, "Deployment 5 to "+d.Environment+" failed", lr.Body().Str())
+	deploymentId, _ := lr.Attributes().Get(conventionsAttributeDeploymentId)
+	assert.Equal(t, "5", deploymentId.Str())
+	jobId, _ := lr.Attributes().Get(conventionsAttributeCiCdTaskRunId)
+	assert.Equal(t, "13", jobId.Str())
+}
+
+func TestMergeRequestEventNewLogs(t *testing.T) {
+	mr := newMergeRequestEvent("update", "2024-01-01 11:00:00 UTC")
+
+	logs, err := mr.newLogs()
+	require.NoError(t, err)
+	require.Equal(t, 1, logs.LogRecordCount())
+
+	lr := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, plog.SeverityNumberInfo, lr.SeverityNumber())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 11:00:00 UTC"), lr.Timestamp())
+	assert.Equal(t, "Merge Request !7 update", lr.Body().Str())
+	iid, _ := lr.Attributes().Get(conventionsAttributeMergeRequestIid)
+	assert.Equal(t, "7", iid.Str())
+
+	//Log records of all events of the merge request belong to the merge request trace
+	traceId, err := getMergeRequestTraceId("42", "7")
+	require.NoError(t, err)
+	assert.Equal(t, pcommon.TraceID(traceId), lr.TraceID())
+	assert.True(t, lr.SpanID().IsEmpty())
+}
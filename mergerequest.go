@@ -0,0 +1,174 @@
+package gitlabreceiver
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Actions of merge request hooks which are exported as lifecycle spans. Other actions (e.g. update) are ignored.
+var mergeRequestLifecycleActions = map[string]bool{
+	"open":       true,
+	"reopen":     true,
+	"approval":   true,
+	"approved":   true,
+	"unapproval": true,
+	"unapproved": true,
+	"merge":      true,
+	"close":      true,
+}
+
+func (mr *glMergeRequestEvent) key() mergeRequestKey {
+	return mergeRequestKey{projectId: mr.Project.Id, iid: mr.MergeRequest.Iid}
+}
+
+func (mr *glMergeRequestEvent) isLifecycleEvent() bool {
+	return mergeRequestLifecycleActions[mr.MergeRequest.Action]
+}
+
+// Merge request hooks don't carry the time of the action, the update time of the merge request is used instead
+func (mr *glMergeRequestEvent) eventTime() (pcommon.Timestamp, error) {
+	return parseGitlabTime(mr.MergeRequest.UpdatedAt)
+}
+
+// The merged or closed merge request is finished, its root span can be exported
+func (mr *glMergeRequestEvent) isFinished() bool {
+	return mr.MergeRequest.Action == "merge" || mr.MergeRequest.Action == "close"
+}
+
+// Every merge request is a long-lived trace, its lifecycle events are exported as they are received. A lifecycle span
+// covers the time since the previous lifecycle event (e.g. the time until the merge request was approved), the root
+// span is exported once the merge request is merged or closed and links to the pipelines of the merge request.
+func (mr *glMergeRequestEvent) newTrace() (*ptrace.Traces, error) {
+	projectId := strconv.Itoa(mr.Project.Id)
+	iid := strconv.Itoa(mr.MergeRequest.Iid)
+	traceId, err := getMergeRequestTraceId(projectId, iid)
+	if err != nil {
+		return nil, err
+	}
+	rootSpanId, err := getMergeRequestRootSpanId(projectId, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := parseGitlabTime(mr.MergeRequest.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := mr.eventTime()
+	if err != nil {
+		return nil, err
+	}
+
+	trace := ptrace.NewTraces()
+	rs := trace.ResourceSpans().AppendEmpty()
+	setProjectResource(rs.Resource(), mr.Project)
+	rs.Resource().Attributes().PutStr(conventionsAttributeSpanSource, fmt.Sprintf("%s-receiver", typeStr.String()))
+
+	if mr.isFinished() {
+		name := fmt.Sprintf("Gitlab Merge Request: !%s - %s", iid, mr.MergeRequest.Url)
+		rootSpan := createSpan(rs, traceId, rootSpanId, [8]byte{0, 0, 0, 0, 0, 0, 0, 0}, name, createdAt, updatedAt, mr)
+		for _, l := range mr.pipelines {
+			addPipelineLink(rootSpan, l, "pipeline")
+		}
+	}
+
+	// The first lifecycle event starts with the creation of the merge request
+	startedAt := mr.previousEventAt
+	if startedAt == 0 || startedAt > updatedAt {
+		startedAt = createdAt
+	}
+	spanId, err := getMergeRequestEventSpanId(projectId, iid, mr.MergeRequest.Action, strconv.FormatUint(uint64(updatedAt), 10))
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("Merge Request: %s - !%s", mr.MergeRequest.Action, iid)
+	createSpan(rs, traceId, spanId, rootSpanId, name, startedAt, updatedAt, mr)
+	return &trace, nil
+}
+
+func (mr *glMergeRequestEvent) setAttributes(s ptrace.Span) {
+	mr.putAttributes(s.Attributes())
+	s.Status().SetCode(ptrace.StatusCodeOk)
+	s.Status().SetMessage(mr.MergeRequest.State)
+}
+
+func (mr *glMergeRequestEvent) putAttributes(attrs pcommon.Map) {
+	attrs.EnsureCapacity(11)
+	attrs.PutStr(conventionsAttributeMergeRequestId, strconv.Itoa(mr.MergeRequest.Id))
+	attrs.PutStr(conventionsAttributeMergeRequestIid, strconv.Itoa(mr.MergeRequest.Iid))
+	attrs.PutStr(conventionsAttributeMergeRequestTitle, mr.MergeRequest.Title)
+	attrs.PutStr(conventionsAttributeMergeRequestUrl, mr.MergeRequest.Url)
+	attrs.PutStr(conventionsAttributeMergeRequestSourceBranch, mr.MergeRequest.SourceBranch)
+	attrs.PutStr(conventionsAttributeMergeRequestTargetBranch, mr.MergeRequest.TargetBranch)
+	attrs.PutStr(conventionsAttributeMergeRequestState, mr.MergeRequest.State)
+	attrs.PutStr(conventionsAttributeMergeRequestAction, mr.MergeRequest.Action)
+	attrs.PutStr(conventionsAttributeMergeRequestAuthorId, strconv.Itoa(mr.MergeRequest.AuthorId))
+	attrs.PutStr(conventionsAttributeMergeRequestUser, mr.User.Username)
+	labels := attrs.PutEmptySlice(conventionsAttributeMergeRequestLabels)
+	labels.EnsureCapacity(len(mr.Labels))
+	for _, l := range mr.Labels {
+		labels.AppendEmpty().SetStr(l.Title)
+	}
+}
+
+// Attributes of the merge request of a merge request pipeline, set on the pipeline span and its resource
+func (mr MergeRequest) putAttributes(attrs pcommon.Map) {
+	attrs.PutStr(conventionsAttributeMergeRequestId, strconv.Itoa(mr.Id))
+	attrs.PutStr(conventionsAttributeMergeRequestIid, strconv.Itoa(mr.Iid))
+	attrs.PutStr(conventionsAttributeMergeRequestTitle, mr.Title)
+	attrs.PutStr(conventionsAttributeMergeRequestUrl, mr.Url)
+	attrs.PutStr(conventionsAttributeMergeRequestSourceBranch, mr.SourceBranch)
+	attrs.PutStr(conventionsAttributeMergeRequestTargetBranch, mr.TargetBranch)
+	attrs.PutStr(conventionsAttributeMergeRequestState, mr.State)
+	attrs.PutStr(conventionsAttributeMergeRequestMergeStatus, mr.DetailedMergeStatus)
+}
+
+// Merge request pipelines run for refs/merge-requests/<iid>/head (or the merge ref), they match the configured refs by
+// the target branch of their merge request
+func (p *glPipelineEvent) refs() []ref {
+	refs := []ref{{name: p.Pipeline.Ref, tag: p.Pipeline.Tag}}
+	if p.MergeRequest.Iid != 0 && p.MergeRequest.TargetBranch != "" {
+		refs = append(refs, ref{name: p.MergeRequest.TargetBranch})
+	}
+	return refs
+}
+
+// The merge request of a merge request pipeline belongs to the target project, which differs from the project of the
+// pipeline for merge requests from forks
+func (p *glPipelineEvent) mergeRequestKey() (mergeRequestKey, bool) {
+	if p.MergeRequest.Iid == 0 {
+		return mergeRequestKey{}, false
+	}
+	projectId := p.MergeRequest.TargetProjectId
+	if projectId == 0 {
+		projectId = p.Project.Id
+	}
+	return mergeRequestKey{projectId: projectId, iid: p.MergeRequest.Iid}, true
+}
+
+// The root span of a merge request pipeline links to the root span of the merge request trace
+func (p *glPipelineEvent) linkMergeRequest(rootSpan ptrace.Span) error {
+	key, ok := p.mergeRequestKey()
+	if !ok {
+		return nil
+	}
+	projectId := strconv.Itoa(key.projectId)
+	iid := strconv.Itoa(key.iid)
+	traceId, err := getMergeRequestTraceId(projectId, iid)
+	if err != nil {
+		return err
+	}
+	spanId, err := getMergeRequestRootSpanId(projectId, iid)
+	if err != nil {
+		return err
+	}
+	link := rootSpan.Links().AppendEmpty()
+	link.SetTraceID(traceId)
+	link.SetSpanID(spanId)
+	link.Attributes().PutStr(conventionsAttributeMergeRequestIid, iid)
+	link.Attributes().PutStr(conventionsAttributeCiCdPipelineLinkType, "merge_request")
+	return nil
+}
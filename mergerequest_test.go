@@ -0,0 +1,238 @@
+package gitlabreceiver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestMergeRequestEventNewTrace(t *testing.T) {
+	mr := newMergeRequestEvent("open", "2024-01-01 10:00:00 UTC")
+	traces, err := mr.newTrace()
+	require.NoError(t, err)
+
+	//Only the lifecycle span is exported while the merge request is open
+	spans := collectSpans(*traces)
+	require.Len(t, spans, 1)
+	traceId, err := getMergeRequestTraceId("42", "7")
+	require.NoError(t, err)
+	rootSpanId, err := getMergeRequestRootSpanId("42", "7")
+	require.NoError(t, err)
+	open := spans["Merge Request: open - !7"]
+	assert.Equal(t, pcommon.TraceID(traceId), open.TraceID())
+	assert.Equal(t, pcommon.SpanID(rootSpanId), open.ParentSpanID())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 10:00:00 UTC"), open.StartTimestamp())
+	targetBranch, _ := open.Attributes().Get(conventionsAttributeMergeRequestTargetBranch)
+	assert.Equal(t, "main", targetBranch.Str())
+	labels, _ := open.Attributes().Get(conventionsAttributeMergeRequestLabels)
+	assert.Equal(t, []any{"backend"}, labels.Slice().AsRaw())
+
+	//The merged merge request exports its root span with links to its pipelines
+	mr = newMergeRequestEvent("merge", "2024-01-01 14:00:00 UTC")
+	mr.MergeRequest.State = "merged"
+	mr.previousEventAt = getParsedGitlabTime("2024-01-01 12:00:00 UTC")
+	mr.pipelines = []pipelineLink{{pipelineId: 1, traceId: [16]byte{1}, spanId: [8]byte{2}}}
+	traces, err = mr.newTrace()
+	require.NoError(t, err)
+	spans = collectSpans(*traces)
+	require.Len(t, spans, 2)
+
+	root := spans["Gitlab Merge Request: !7 - https://gitlab.com/group/project/-/merge_requests/7"]
+	assert.Equal(t, pcommon.SpanID(rootSpanId), root.SpanID())
+	assert.True(t, root.ParentSpanID().IsEmpty())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 10:00:00 UTC"), root.StartTimestamp())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 14:00:00 UTC"), root.EndTimestamp())
+	assert.Equal(t, "merged", root.Status().Message())
+	require.Equal(t, 1, root.Links().Len())
+	assert.Equal(t, pcommon.TraceID([16]byte{1}), root.Links().At(0).TraceID())
+
+	merge := spans["Merge Request: merge - !7"]
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:00:00 UTC"), merge.StartTimestamp(), "the lifecycle span starts with the previous event")
+	assert.Equal(t, root.EndTimestamp(), merge.EndTimestamp())
+}
+
+func TestPipelineEventMergeRequest(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.MergeRequest = newPipelineMergeRequest()
+	traces, err := p.newTrace()
+	require.NoError(t, err)
+
+	root := collectSpans(*traces)["Gitlab Pipeline: 1 - https://gitlab.com/group/project/-/pipelines/1"]
+	for _, attrs := range []pcommon.Map{root.Attributes(), traces.ResourceSpans().At(0).Resource().Attributes()} {
+		iid, _ := attrs.Get(conventionsAttributeMergeRequestIid)
+		assert.Equal(t, "7", iid.Str())
+		url, _ := attrs.Get(conventionsAttributeMergeRequestUrl)
+		assert.Equal(t, "https://gitlab.com/group/project/-/merge_requests/7", url.Str())
+		sourceBranch, _ := attrs.Get(conventionsAttributeMergeRequestSourceBranch)
+		assert.Equal(t, "feature", sourceBranch.Str())
+		targetBranch, _ := attrs.Get(conventionsAttributeMergeRequestTargetBranch)
+		assert.Equal(t, "main", targetBranch.Str())
+		mergeStatus, _ := attrs.Get(conventionsAttributeMergeRequestMergeStatus)
+		assert.Equal(t, "mergeable", mergeStatus.Str())
+	}
+
+	//The pipeline links to the trace of its merge request
+	traceId, err := getMergeRequestTraceId("42", "7")
+	require.NoError(t, err)
+	rootSpanId, err := getMergeRequestRootSpanId("42", "7")
+	require.NoError(t, err)
+	require.Equal(t, 1, root.Links().Len())
+	assert.Equal(t, pcommon.TraceID(traceId), root.Links().At(0).TraceID())
+	assert.Equal(t, pcommon.SpanID(rootSpanId), root.Links().At(0).SpanID())
+
+	//Merge requests from forks belong to the target project
+	p.Project.Id = 43
+	key, ok := p.mergeRequestKey()
+	assert.True(t, ok)
+	assert.Equal(t, mergeRequestKey{projectId: 42, iid: 7}, key)
+}
+
+func TestDecodePipelineEventMergeRequest(t *testing.T) {
+	body := `{"object_kind": "pipeline", "object_attributes": {"id": 1, "ref": "refs/merge-requests/7/head"}, "merge_request": {
+		"id": 99, "iid": 7, "title": "Add feature", "source_branch": "feature", "source_project_id": 43, "target_branch": "main",
+		"target_project_id": 42, "state": "opened", "merge_status": "can_be_merged", "detailed_merge_status": "mergeable",
+		"url": "https://gitlab.com/group/project/-/merge_requests/7"}}`
+	p, err := decode[glPipelineEvent]([]byte(body))
+	require.NoError(t, err)
+	expected := newPipelineMergeRequest()
+	expected.SourceProjectId = 43
+	assert.Equal(t, expected, p.MergeRequest)
+	assert.Equal(t, []ref{{name: "refs/merge-requests/7/head"}, {name: "main"}}, p.refs())
+}
+
+func TestGitlabReceiverMergeRequestPipelineRefs(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.Refs = []string{"main"}
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	//Merge request pipelines match the configured refs by their target branch
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Ref = "refs/merge-requests/7/head"
+	p.MergeRequest = newPipelineMergeRequest()
+	res := sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, "OK", res.Body.String())
+
+	p = newFinishedPipelineEvent()
+	p.Pipeline.Id = 2
+	p.Pipeline.Ref = "refs/merge-requests/8/head"
+	p.MergeRequest = newPipelineMergeRequest()
+	p.MergeRequest.Iid = 8
+	p.MergeRequest.TargetBranch = "develop"
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+func TestGitlabReceiverMergeRequestPipelineExcludeRefs(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.ExcludeRefs = []string{"main"}
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Ref = "main"
+	res := sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+
+	//Merge request pipelines aren't excluded by the target branch of their merge request
+	p = newFinishedPipelineEvent()
+	p.Pipeline.Id = 2
+	p.Pipeline.Ref = "refs/merge-requests/7/head"
+	p.MergeRequest = newPipelineMergeRequest()
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, "OK", res.Body.String())
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+func TestGitlabReceiverMergeRequest(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, createDefaultConfig().(*Config), receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	res := sendEvent(t, glRcvr, "Merge Request Hook", newMergeRequestEvent("open", "2024-01-01 10:00:00 UTC"))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	//Updates aren't part of the lifecycle
+	res = sendEvent(t, glRcvr, "Merge Request Hook", newMergeRequestEvent("update", "2024-01-01 11:00:00 UTC"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+
+	p := newFinishedPipelineEvent()
+	p.MergeRequest = MergeRequest{Id: 99, Iid: 7, TargetProjectId: 42}
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = sendEvent(t, glRcvr, "Merge Request Hook", newMergeRequestEvent("approved", "2024-01-01 12:00:00 UTC"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	merged := newMergeRequestEvent("merge", "2024-01-01 14:00:00 UTC")
+	res = sendEvent(t, glRcvr, "Merge Request Hook", merged)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 4)
+
+	approved := collectSpans(sink.AllTraces()[2])["Merge Request: approved - !7"]
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 10:00:00 UTC"), approved.StartTimestamp(), "the approval covers the time since the merge request was opened")
+	spans := collectSpans(sink.AllTraces()[3])
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:00:00 UTC"), spans["Merge Request: merge - !7"].StartTimestamp())
+
+	pipelineTraceId, _, err := p.traceContext()
+	require.NoError(t, err)
+	root := spans["Gitlab Merge Request: !7 - https://gitlab.com/group/project/-/merge_requests/7"]
+	require.Equal(t, 1, root.Links().Len())
+	assert.Equal(t, pcommon.TraceID(pipelineTraceId), root.Links().At(0).TraceID())
+
+	//A redelivered event has the same start
+	res = sendEvent(t, glRcvr, "Merge Request Hook", newMergeRequestEvent("approved", "2024-01-01 12:00:00 UTC"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	redelivered := collectSpans(sink.AllTraces()[4])["Merge Request: approved - !7"]
+	assert.Equal(t, approved.SpanID(), redelivered.SpanID())
+	assert.Equal(t, approved.StartTimestamp(), redelivered.StartTimestamp())
+}
+
+func newPipelineMergeRequest() MergeRequest {
+	return MergeRequest{
+		Id:                  99,
+		Iid:                 7,
+		Title:               "Add feature",
+		SourceBranch:        "feature",
+		TargetBranch:        "main",
+		TargetProjectId:     42,
+		State:               "opened",
+		DetailedMergeStatus: "mergeable",
+		Url:                 "https://gitlab.com/group/project/-/merge_requests/7",
+	}
+}
+
+func newMergeRequestEvent(action string, updatedAt string) *glMergeRequestEvent {
+	return &glMergeRequestEvent{
+		Kind: "merge_request",
+		User: User{Id: 1, Name: "User", Username: "user"},
+		Project: Project{
+			Id:   42,
+			Name: "project",
+			Path: "group/project",
+			Url:  "https://gitlab.com/group/project",
+		},
+		MergeRequest: MergeRequestAttributes{
+			Id:           99,
+			Iid:          7,
+			Title:        "Add feature",
+			SourceBranch: "feature",
+			TargetBranch: "main",
+			State:        "opened",
+			Action:       action,
+			AuthorId:     1,
+			Url:          "https://gitlab.com/group/project/-/merge_requests/7",
+			CreatedAt:    "2024-01-01 10:00:00 UTC",
+			UpdatedAt:    updatedAt,
+		},
+		Labels: []Label{{Id: 1, Title: "backend"}},
+	}
+}
@@ -0,0 +1,188 @@
+package gitlabreceiver
+
+import (
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	metricPipelineDuration       = "cicd.pipeline.run.duration"
+	metricPipelineQueuedDuration = "cicd.pipeline.run.queued.duration"
+	metricPipelineRuns           = "cicd.pipeline.run.count"
+	metricJobDuration            = "cicd.job.run.duration"
+	metricJobQueuedDuration      = "cicd.job.run.queued.duration"
+	metricJobRuns                = "cicd.job.run.count"
+	metricRunnerUsage            = "cicd.runner.usage"
+)
+
+var supportedMetrics = []string{
+	metricPipelineDuration,
+	metricPipelineQueuedDuration,
+	metricPipelineRuns,
+	metricJobDuration,
+	metricJobQueuedDuration,
+	metricJobRuns,
+	metricRunnerUsage,
+}
+
+// Bucket boundaries in seconds, pipelines and jobs usually take between a few seconds and a few hours
+var durationBuckets = []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400}
+
+// Every finished pipeline creates one data point per metric and attribute set. The data points are deltas over the run
+// of the pipeline or job, aggregating them over time (e.g. counting pipelines by status) is up to the backend.
+func (p *glPipelineEvent) newMetrics(cfg Metrics) (pmetric.Metrics, error) {
+	metrics := pmetric.NewMetrics()
+	rm := metrics.ResourceMetrics().AppendEmpty()
+	setProjectResource(rm.Resource(), p.Project)
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(typeStr.String() + "receiver")
+
+	finishedAt, err := parseGitlabTime(p.Pipeline.FinishedAt)
+	if err != nil {
+		return metrics, err
+	}
+	// Like the pipeline span, the pipeline run starts when it is created
+	createdAt, err := parseStartTime(p.Pipeline.CreatedAt, finishedAt)
+	if err != nil {
+		return metrics, err
+	}
+
+	pipelineAttrs := pcommon.NewMap()
+	pipelineAttrs.PutStr(conventionsAttributeCiCdPipelineRef, p.Pipeline.Ref)
+	pipelineAttrs.PutStr(conventionsAttributeCiCdPipelineStatus, p.Pipeline.Status)
+	pipelineAttrs.PutStr(conventionsAttributeCiCdPipelineSource, p.Pipeline.Source)
+
+	m := newHistogram(sm, metricPipelineDuration, "Duration of finished pipelines", "s")
+	addHistogramDataPoint(m, pipelineAttrs, createdAt, finishedAt, float64(p.Pipeline.Duration))
+	m = newHistogram(sm, metricPipelineQueuedDuration, "Time finished pipelines were queued before they started", "s")
+	addHistogramDataPoint(m, pipelineAttrs, createdAt, finishedAt, float64(p.Pipeline.QueuedDuration))
+	m = newSum(sm, metricPipelineRuns, "Number of finished pipelines", "{run}")
+	addCountDataPoint(m, pipelineAttrs, createdAt, finishedAt)
+
+	jobDuration := newHistogram(sm, metricJobDuration, "Duration of finished jobs", "s")
+	jobQueuedDuration := newHistogram(sm, metricJobQueuedDuration, "Time finished jobs were waiting for a runner", "s")
+	jobRuns := newSum(sm, metricJobRuns, "Number of finished jobs", "{run}")
+	runnerUsage := newSum(sm, metricRunnerUsage, "Time runners spent executing jobs", "s")
+
+	for _, j := range p.Jobs {
+		if j.FinishedAt == "" {
+			continue
+		}
+		jobFinishedAt, err := parseGitlabTime(j.FinishedAt)
+		if err != nil {
+			return metrics, err
+		}
+		jobStartedAt, err := parseStartTime(j.StartedAt, jobFinishedAt)
+		if err != nil {
+			return metrics, err
+		}
+
+		jobAttrs := pcommon.NewMap()
+		jobAttrs.PutStr(conventionsAttributeCiCdPipelineRef, p.Pipeline.Ref)
+		jobAttrs.PutStr(conventionsAttributeCiCdJobName, j.Name)
+		jobAttrs.PutStr(conventionsAttributeCiCdJobStage, j.Stage)
+		jobAttrs.PutStr(conventionsAttributeCiCdJobStatus, j.Status)
+		addHistogramDataPoint(jobDuration, jobAttrs, jobStartedAt, jobFinishedAt, j.Duration)
+		addHistogramDataPoint(jobQueuedDuration, jobAttrs, jobStartedAt, jobFinishedAt, j.QueuedDuration)
+		addCountDataPoint(jobRuns, jobAttrs, jobStartedAt, jobFinishedAt)
+
+		if j.Runner.Id != 0 {
+			runnerAttrs := pcommon.NewMap()
+			runnerAttrs.PutStr(conventionsAttributeCiCdJobRunnerId, strconv.Itoa(j.Runner.Id))
+			runnerAttrs.PutStr(conventionsAttributeCiCdJobRunnerDescription, j.Runner.Description)
+			runnerAttrs.PutStr(conventionsAttributeCiCdJobRunnerType, j.Runner.Type)
+			runnerAttrs.PutStr(conventionsAttributeCiCdJobRunnerIsShared, strconv.FormatBool(j.Runner.IsShared))
+			addSumDataPoint(runnerUsage, runnerAttrs, jobStartedAt, jobFinishedAt, j.Duration)
+		}
+	}
+
+	sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+		return !cfg.isEnabled(m.Name()) || dataPointCount(m) == 0
+	})
+	return metrics, nil
+}
+
+func newHistogram(sm pmetric.ScopeMetrics, name string, description string, unit string) pmetric.Metric {
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(name)
+	m.SetDescription(description)
+	m.SetUnit(unit)
+	m.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	return m
+}
+
+func newSum(sm pmetric.ScopeMetrics, name string, description string, unit string) pmetric.Metric {
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(name)
+	m.SetDescription(description)
+	m.SetUnit(unit)
+	sum := m.SetEmptySum()
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	sum.SetIsMonotonic(true)
+	return m
+}
+
+// Runs without a start time (e.g. jobs which were canceled before they started) are reported at their end
+func parseStartTime(start string, end pcommon.Timestamp) (pcommon.Timestamp, error) {
+	ts, err := parseGitlabTime(start)
+	if err != nil || ts == 0 {
+		return end, err
+	}
+	return ts, nil
+}
+
+func addHistogramDataPoint(m pmetric.Metric, attrs pcommon.Map, start pcommon.Timestamp, ts pcommon.Timestamp, value float64) {
+	dp := m.Histogram().DataPoints().AppendEmpty()
+	attrs.CopyTo(dp.Attributes())
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(ts)
+	dp.SetCount(1)
+	dp.SetSum(value)
+	dp.SetMin(value)
+	dp.SetMax(value)
+	dp.ExplicitBounds().FromRaw(durationBuckets)
+	bucketCounts := make([]uint64, len(durationBuckets)+1)
+	bucketCounts[bucketIndex(durationBuckets, value)] = 1
+	dp.BucketCounts().FromRaw(bucketCounts)
+}
+
+func addSumDataPoint(m pmetric.Metric, attrs pcommon.Map, start pcommon.Timestamp, ts pcommon.Timestamp, value float64) {
+	dp := m.Sum().DataPoints().AppendEmpty()
+	attrs.CopyTo(dp.Attributes())
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(value)
+}
+
+// Every data point counts a single run
+func addCountDataPoint(m pmetric.Metric, attrs pcommon.Map, start pcommon.Timestamp, ts pcommon.Timestamp) {
+	dp := m.Sum().DataPoints().AppendEmpty()
+	attrs.CopyTo(dp.Attributes())
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(ts)
+	dp.SetIntValue(1)
+}
+
+// Buckets are upper inclusive: (bounds[i-1], bounds[i]]
+func bucketIndex(bounds []float64, value float64) int {
+	for i, b := range bounds {
+		if value <= b {
+			return i
+		}
+	}
+	return len(bounds)
+}
+
+func dataPointCount(m pmetric.Metric) int {
+	switch m.Type() {
+	case pmetric.MetricTypeHistogram:
+		return m.Histogram().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		return m.Sum().DataPoints().Len()
+	case pmetric.MetricTypeGauge:
+		return m.Gauge().DataPoints().Len()
+	}
+	return 0
+}
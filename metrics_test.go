@@ -0,0 +1,105 @@
+package gitlabreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPipelineEventNewMetrics(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Duration = 600
+	p.Pipeline.QueuedDuration = 20
+	p.Jobs[0].Duration = 45
+	p.Jobs[0].Runner = Runner{Id: 7, Description: "shared-runner", Type: "instance_type", IsShared: true}
+
+	metrics, err := p.newMetrics(Metrics{})
+	require.NoError(t, err)
+
+	rm := metrics.ResourceMetrics().At(0)
+	serviceName, _ := rm.Resource().Attributes().Get("service.name")
+	assert.Equal(t, "group/project", serviceName.Str())
+
+	got := collectMetrics(metrics)
+	assert.Len(t, got, len(supportedMetrics), "all metrics are enabled by default")
+
+	pipelineDuration := got[metricPipelineDuration].Histogram().DataPoints().At(0)
+	assert.Equal(t, float64(600), pipelineDuration.Sum())
+	assert.Equal(t, uint64(1), pipelineDuration.BucketCounts().At(bucketIndex(durationBuckets, 600)))
+	status, _ := pipelineDuration.Attributes().Get(conventionsAttributeCiCdPipelineStatus)
+	assert.Equal(t, "failed", status.Str())
+	ref, _ := pipelineDuration.Attributes().Get(conventionsAttributeCiCdPipelineRef)
+	assert.Equal(t, "main", ref.Str())
+
+	//The deltas cover the run from its start until its end
+	startTime, err := parseGitlabTime(gitlabStartTime)
+	require.NoError(t, err)
+	endTime, err := parseGitlabTime(gitlabEndTime)
+	require.NoError(t, err)
+	assert.Equal(t, startTime, pipelineDuration.StartTimestamp())
+	assert.Equal(t, endTime, pipelineDuration.Timestamp())
+
+	pipelineRuns := got[metricPipelineRuns].Sum().DataPoints()
+	require.Equal(t, 1, pipelineRuns.Len())
+	assert.Equal(t, pmetric.AggregationTemporalityDelta, got[metricPipelineRuns].Sum().AggregationTemporality())
+	assert.Equal(t, pmetric.NumberDataPointValueTypeInt, pipelineRuns.At(0).ValueType())
+	assert.Equal(t, int64(1), pipelineRuns.At(0).IntValue())
+	assert.Equal(t, startTime, pipelineRuns.At(0).StartTimestamp())
+
+	//Only finished jobs are counted
+	assert.Equal(t, 2, got[metricJobDuration].Histogram().DataPoints().Len())
+	jobRuns := got[metricJobRuns].Sum().DataPoints()
+	assert.Equal(t, 2, jobRuns.Len())
+	stage, _ := jobRuns.At(0).Attributes().Get(conventionsAttributeCiCdJobStage)
+	assert.Equal(t, "test", stage.Str())
+	assert.Equal(t, int64(1), jobRuns.At(0).IntValue())
+	assert.Equal(t, startTime, jobRuns.At(0).StartTimestamp())
+	assert.Equal(t, endTime, jobRuns.At(0).Timestamp())
+
+	//Only jobs with a runner are considered for the runner usage
+	runnerUsage := got[metricRunnerUsage].Sum().DataPoints()
+	require.Equal(t, 1, runnerUsage.Len())
+	assert.Equal(t, float64(45), runnerUsage.At(0).DoubleValue())
+	runner, _ := runnerUsage.At(0).Attributes().Get(conventionsAttributeCiCdJobRunnerDescription)
+	assert.Equal(t, "shared-runner", runner.Str())
+}
+
+func TestPipelineEventNewMetricsDisabled(t *testing.T) {
+	p := newFinishedPipelineEvent()
+
+	metrics, err := p.newMetrics(Metrics{Enabled: map[string]bool{
+		metricJobDuration: false,
+		metricJobRuns:     true,
+	}})
+	require.NoError(t, err)
+
+	got := collectMetrics(metrics)
+	assert.NotContains(t, got, metricJobDuration)
+	assert.Contains(t, got, metricJobRuns)
+	assert.NotContains(t, got, metricRunnerUsage, "metrics without data points are removed")
+}
+
+func TestBucketIndex(t *testing.T) {
+	bounds := []float64{5, 10}
+	assert.Equal(t, 0, bucketIndex(bounds, 0))
+	assert.Equal(t, 0, bucketIndex(bounds, 5))
+	assert.Equal(t, 1, bucketIndex(bounds, 7))
+	assert.Equal(t, 2, bucketIndex(bounds, 11))
+}
+
+// Metrics of all resource and scope metrics by metric name
+func collectMetrics(metrics pmetric.Metrics) map[string]pmetric.Metric {
+	got := make(map[string]pmetric.Metric)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		rm := metrics.ResourceMetrics().At(i)
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				got[sm.Metrics().At(k).Name()] = sm.Metrics().At(k)
+			}
+		}
+	}
+	return got
+}
@@ -1,5 +1,7 @@
 package gitlabreceiver
 
+import "go.opentelemetry.io/collector/pdata/pcommon"
+
 type glJobEvent struct {
 	Kind           string  `json:"object_kind"`
 	Sha            string  `json:"sha"`
@@ -12,6 +14,7 @@ type glJobEvent struct {
 	StartedAt      string  `json:"build_started_at"`
 	FinishedAt     string  `json:"build_finished_at"`
 	Duration       float64 `json:"build_duration"`
+	QueuedDuration float64 `json:"build_queued_duration"`
 	FailureReason  string  `json:"build_failure_reason"`
 	PipelineId     int     `json:"pipeline_id"`
 	JobUrl         string
@@ -19,6 +22,62 @@ type glJobEvent struct {
 	ParentPipeline ParentPipeline `json:"source_pipeline"`
 	Repository     Repository     `json:"repository"`
 	Project        Project        `json:"project"`
+	Runner         Runner         `json:"runner"`
+	Environment    Environment    `json:"environment"`
+	traceId        [16]byte
+	spanId         [8]byte
+	parentSpanId   [8]byte
+	jobPhases      bool
+}
+
+type glDeploymentEvent struct {
+	Kind                   string  `json:"object_kind"`
+	Id                     int     `json:"deployment_id"`
+	Status                 string  `json:"status"`
+	StatusChangedAt        string  `json:"status_changed_at"`
+	DeployableId           int     `json:"deployable_id"`
+	DeployableUrl          string  `json:"deployable_url"`
+	Environment            string  `json:"environment"`
+	EnvironmentTier        string  `json:"environment_tier"`
+	EnvironmentExternalUrl string  `json:"environment_external_url"`
+	ShortSha               string  `json:"short_sha"`
+	CommitUrl              string  `json:"commit_url"`
+	CommitTitle            string  `json:"commit_title"`
+	Project                Project `json:"project"`
+	User                   User    `json:"user"`
+	startedAt              string
+	traceId                [16]byte
+	spanId                 [8]byte
+	parentSpanId           [8]byte
+}
+
+type glMergeRequestEvent struct {
+	Kind            string                 `json:"object_kind"`
+	User            User                   `json:"user"`
+	Project         Project                `json:"project"`
+	MergeRequest    MergeRequestAttributes `json:"object_attributes"`
+	Labels          []Label                `json:"labels"`
+	previousEventAt pcommon.Timestamp
+	pipelines       []pipelineLink
+}
+
+type MergeRequestAttributes struct {
+	Id           int    `json:"id"`
+	Iid          int    `json:"iid"`
+	Title        string `json:"title"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	State        string `json:"state"`
+	Action       string `json:"action"`
+	AuthorId     int    `json:"author_id"`
+	Url          string `json:"url"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+type Label struct {
+	Id    int    `json:"id"`
+	Title string `json:"title"`
 }
 
 type Repository struct {
@@ -41,12 +100,22 @@ type glPipelineEvent struct {
 	ParentPipeline ParentPipeline `json:"source_pipeline"`
 	User           User           `json:"user"`
 	Commit         Commit         `json:"commit"`
+	MergeRequest   MergeRequest   `json:"merge_request"`
+	jobEvents      map[int]*glJobEvent
+	deployments    map[int]*glDeploymentEvent
+	attempts       pipelineAttempts
+	upstream       *pipelineLink
+	downstream     []pipelineLink
+	stageSpans     bool
+	jobPhases      bool
+	stableTraceId  bool
 }
 
 type Pipeline struct {
 	Id             int         `json:"id"`
 	Status         string      `json:"status"`
 	Ref            string      `json:"ref"`
+	Tag            bool        `json:"tag"`
 	Url            string      `json:"url"`
 	CreatedAt      string      `json:"created_at"`
 	FinishedAt     string      `json:"finished_at"`
@@ -57,6 +126,20 @@ type Pipeline struct {
 	Variables      []Variables `json:"variables"`
 }
 
+// Merge request of a merge request pipeline, empty for other pipelines
+type MergeRequest struct {
+	Id                  int    `json:"id"`
+	Iid                 int    `json:"iid"`
+	Title               string `json:"title"`
+	SourceBranch        string `json:"source_branch"`
+	SourceProjectId     int    `json:"source_project_id"`
+	TargetBranch        string `json:"target_branch"`
+	TargetProjectId     int    `json:"target_project_id"`
+	State               string `json:"state"`
+	DetailedMergeStatus string `json:"detailed_merge_status"`
+	Url                 string `json:"url"`
+}
+
 type Variables struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -64,22 +147,24 @@ type Variables struct {
 
 type ParentPipeline struct {
 	Id      int     `json:"pipeline_id"`
+	JobId   int     `json:"job_id"` //Bridge job which triggered the pipeline
 	Project Project `json:"project"`
 }
 
 type Job struct {
-	Id          int    `json:"id"`
-	Name        string `json:"name"`
-	Status      string `json:"status"`
-	Stage       string `json:"stage"`
-	CreatedAt   string `json:"created_at"`
-	StartedAt   string `json:"started_at"`
-	FinishedAt  string `json:"finished_at"`
-	Url         string
-	ProjectPath string
-	Runner      Runner      `json:"runner"`
-	Environment Environment `json:"environment"`
-	Duration    float64     `json:"duration"`
+	Id             int    `json:"id"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	Stage          string `json:"stage"`
+	CreatedAt      string `json:"created_at"`
+	StartedAt      string `json:"started_at"`
+	FinishedAt     string `json:"finished_at"`
+	Url            string
+	ProjectPath    string
+	Runner         Runner      `json:"runner"`
+	Environment    Environment `json:"environment"`
+	Duration       float64     `json:"duration"`
+	QueuedDuration float64     `json:"queued_duration"`
 }
 
 type User struct {
@@ -113,5 +198,7 @@ type Runner struct {
 }
 
 type Environment struct {
-	Name string `json:"name"`
+	Name           string `json:"name"`
+	Action         string `json:"action"`
+	DeploymentTier string `json:"deployment_tier"`
 }
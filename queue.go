@@ -0,0 +1,218 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	tracesQueueStorageName = "traces_queue"
+	queueReadIndexKey      = "ri"
+	queueWriteIndexKey     = "wi"
+	queueItemKeyPrefix     = "item/"
+)
+
+var errQueueFull = errors.New("traces queue is full")
+
+// tracesQueue persists the traces in the storage extension before they are consumed, so that webhooks are acknowledged
+// independently of the state of the pipeline. The items are consumed in order by a single goroutine, the read and write
+// index are persisted together with the items so that the queue is restored after a restart of the collector.
+type tracesQueue struct {
+	mu          sync.Mutex
+	cfg         Queue
+	logger      *zap.Logger
+	client      storage.Client
+	consumer    consumer.Traces
+	readIndex   uint64
+	writeIndex  uint64
+	notify      chan struct{}
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	marshaler   ptrace.ProtoMarshaler
+	unmarshaler ptrace.ProtoUnmarshaler
+}
+
+func newTracesQueue(cfg Queue, client storage.Client, consumer consumer.Traces, logger *zap.Logger) *tracesQueue {
+	return &tracesQueue{
+		cfg:      cfg,
+		logger:   logger,
+		client:   client,
+		consumer: consumer,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// The queue is drained until shutdown, the context of the collector start isn't used because it is cancelled once the start is done
+func (q *tracesQueue) start(ctx context.Context) error {
+	var err error
+	q.readIndex, err = q.loadIndex(ctx, queueReadIndexKey)
+	if err != nil {
+		return err
+	}
+	q.writeIndex, err = q.loadIndex(ctx, queueWriteIndexKey)
+	if err != nil {
+		return err
+	}
+	if q.writeIndex > q.readIndex {
+		q.logger.Info("Restored persisted traces", zap.Uint64("items", q.writeIndex-q.readIndex))
+	}
+
+	drainCtx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.drain(drainCtx)
+	}()
+	return nil
+}
+
+// Items which are not consumed yet stay in the storage and are consumed after the next start
+func (q *tracesQueue) shutdown(ctx context.Context) error {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+	return q.client.Close(ctx)
+}
+
+// The traces are enqueued once they are persisted, an error means that they are not going to be consumed
+func (q *tracesQueue) enqueue(ctx context.Context, td ptrace.Traces) error {
+	data, err := q.marshaler.MarshalTraces(td)
+	if err != nil {
+		return fmt.Errorf("unable to encode the traces: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.writeIndex-q.readIndex >= uint64(q.cfg.QueueSize) {
+		return errQueueFull
+	}
+	err = q.client.Batch(ctx,
+		storage.SetOperation(queueItemKey(q.writeIndex), data),
+		storage.SetOperation(queueWriteIndexKey, encodeIndex(q.writeIndex+1)),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to persist the traces: %w", err)
+	}
+	q.writeIndex++
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *tracesQueue) drain(ctx context.Context) {
+	for {
+		q.mu.Lock()
+		index, empty := q.readIndex, q.readIndex == q.writeIndex
+		q.mu.Unlock()
+		if empty {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+				continue
+			}
+		}
+
+		err := q.consumeItem(ctx, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			q.logger.Error("Dropping the queued traces", zap.Uint64("index", index), zap.Error(err))
+		}
+
+		// The drain is the only writer of the read index, the lock isn't held for the storage so that webhooks are
+		// enqueued in the meantime
+		err = q.client.Batch(ctx,
+			storage.DeleteOperation(queueItemKey(index)),
+			storage.SetOperation(queueReadIndexKey, encodeIndex(index+1)),
+		)
+		if err != nil {
+			// The item is consumed again after a restart of the collector
+			q.logger.Warn("Unable to remove the consumed traces from the queue", zap.Uint64("index", index), zap.Error(err))
+		}
+		q.mu.Lock()
+		q.readIndex = index + 1
+		q.mu.Unlock()
+	}
+}
+
+func (q *tracesQueue) consumeItem(ctx context.Context, index uint64) error {
+	data, err := q.client.Get(ctx, queueItemKey(index))
+	if err != nil {
+		return fmt.Errorf("unable to load the traces: %w", err)
+	}
+	if data == nil {
+		return errors.New("traces not found")
+	}
+	td, err := q.unmarshaler.UnmarshalTraces(data)
+	if err != nil {
+		return fmt.Errorf("unable to decode the traces: %w", err)
+	}
+
+	if !q.cfg.RetryOnFailure.Enabled {
+		return q.consumer.ConsumeTraces(ctx, td)
+	}
+	return backoff.Retry(func() error {
+		err := q.consumer.ConsumeTraces(ctx, td)
+		if consumererror.IsPermanent(err) {
+			return backoff.Permanent(err)
+		}
+		if err != nil {
+			q.logger.Debug("Unable to consume the queued traces, retrying", zap.Uint64("index", index), zap.Error(err))
+		}
+		return err
+	}, backoff.WithContext(q.backOff(), ctx))
+}
+
+func (q *tracesQueue) backOff() backoff.BackOff {
+	b := &backoff.ExponentialBackOff{
+		InitialInterval:     q.cfg.RetryOnFailure.InitialInterval,
+		RandomizationFactor: q.cfg.RetryOnFailure.RandomizationFactor,
+		Multiplier:          q.cfg.RetryOnFailure.Multiplier,
+		MaxInterval:         q.cfg.RetryOnFailure.MaxInterval,
+		MaxElapsedTime:      q.cfg.RetryOnFailure.MaxElapsedTime,
+		Stop:                backoff.Stop,
+		Clock:               backoff.SystemClock,
+	}
+	b.Reset()
+	return b
+}
+
+func (q *tracesQueue) loadIndex(ctx context.Context, key string) (uint64, error) {
+	data, err := q.client.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load the traces queue: %w", err)
+	}
+	if data == nil {
+		return 0, nil
+	}
+	if len(data) != 8 {
+		return 0, fmt.Errorf("invalid index %q of the traces queue", key)
+	}
+	return binary.LittleEndian.Uint64(data), nil
+}
+
+func encodeIndex(index uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, index)
+}
+
+func queueItemKey(index uint64) string {
+	return queueItemKeyPrefix + strconv.FormatUint(index, 10)
+}
@@ -0,0 +1,170 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func TestTracesQueue(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	client := newMemoryStorageClient()
+	q := newTracesQueue(newTestQueueConfig(), client, sink, zap.NewNop())
+	require.NoError(t, q.start(context.Background()))
+
+	traces := newTestTraces("pipeline")
+	require.NoError(t, q.enqueue(context.Background(), traces))
+	require.Eventually(t, func() bool { return sink.SpanCount() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, traces, sink.AllTraces()[0])
+
+	require.NoError(t, q.shutdown(context.Background()))
+	assert.Nil(t, client.data[queueItemKey(0)], "consumed items are removed from the storage")
+	assert.Equal(t, encodeIndex(1), client.data[queueReadIndexKey])
+}
+
+func TestTracesQueueRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int64
+		expected []string
+	}{
+		{
+			name:     "retryable error",
+			err:      errors.New("pipeline is backed up"),
+			attempts: 3,
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "permanent error",
+			err:      consumererror.NewPermanent(errors.New("invalid traces")),
+			attempts: 1,
+			expected: []string{"second"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			//The first traces fail twice
+			var attempts atomic.Int64
+			sink := new(consumertest.TracesSink)
+			next, err := consumer.NewTraces(func(ctx context.Context, td ptrace.Traces) error {
+				if spanName(td) == "first" && attempts.Add(1) < 3 {
+					return tc.err
+				}
+				return sink.ConsumeTraces(ctx, td)
+			})
+			require.NoError(t, err)
+
+			q := newTracesQueue(newTestQueueConfig(), newMemoryStorageClient(), next, zap.NewNop())
+			require.NoError(t, q.start(context.Background()))
+			require.NoError(t, q.enqueue(context.Background(), newTestTraces("first")))
+			require.NoError(t, q.enqueue(context.Background(), newTestTraces("second")))
+
+			require.Eventually(t, func() bool { return sink.SpanCount() == len(tc.expected) }, time.Second, 10*time.Millisecond)
+			require.NoError(t, q.shutdown(context.Background()))
+			assert.Equal(t, tc.attempts, attempts.Load())
+			for i, name := range tc.expected {
+				assert.Equal(t, name, spanName(sink.AllTraces()[i]))
+			}
+		})
+	}
+}
+
+func TestTracesQueuePersistence(t *testing.T) {
+	client := newMemoryStorageClient()
+	failing := consumertest.NewErr(errors.New("pipeline is backed up"))
+	q := newTracesQueue(newTestQueueConfig(), client, failing, zap.NewNop())
+	require.NoError(t, q.start(context.Background()))
+	require.NoError(t, q.enqueue(context.Background(), newTestTraces("first")))
+	require.NoError(t, q.enqueue(context.Background(), newTestTraces("second")))
+	require.NoError(t, q.shutdown(context.Background()))
+
+	//A restarted queue consumes the persisted traces in order
+	sink := new(consumertest.TracesSink)
+	restarted := newTracesQueue(newTestQueueConfig(), client, sink, zap.NewNop())
+	require.NoError(t, restarted.start(context.Background()))
+	require.Eventually(t, func() bool { return sink.SpanCount() == 2 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, restarted.shutdown(context.Background()))
+
+	assert.Equal(t, "first", spanName(sink.AllTraces()[0]))
+	assert.Equal(t, "second", spanName(sink.AllTraces()[1]))
+}
+
+func TestTracesQueueFull(t *testing.T) {
+	cfg := newTestQueueConfig()
+	cfg.QueueSize = 1
+	q := newTracesQueue(cfg, newMemoryStorageClient(), consumertest.NewNop(), zap.NewNop())
+
+	//Without start the queue isn't drained
+	require.NoError(t, q.enqueue(context.Background(), newTestTraces("first")))
+	assert.ErrorIs(t, q.enqueue(context.Background(), newTestTraces("second")), errQueueFull)
+}
+
+func TestTracesQueueEnqueueDuringRemoval(t *testing.T) {
+	client := &blockingStorageClient{memoryStorageClient: newMemoryStorageClient(), unblock: make(chan struct{})}
+	sink := new(consumertest.TracesSink)
+	q := newTracesQueue(newTestQueueConfig(), client, sink, zap.NewNop())
+	require.NoError(t, q.start(context.Background()))
+	require.NoError(t, q.enqueue(context.Background(), newTestTraces("first")))
+	require.Eventually(t, func() bool { return sink.SpanCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	//The removal of the consumed traces is blocked, webhooks are still enqueued
+	done := make(chan error)
+	go func() { done <- q.enqueue(context.Background(), newTestTraces("second")) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("enqueue is blocked by the removal of consumed traces")
+	}
+
+	close(client.unblock)
+	require.Eventually(t, func() bool { return sink.SpanCount() == 2 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, q.shutdown(context.Background()))
+}
+
+func newTestQueueConfig() Queue {
+	retry := configretry.NewDefaultBackOffConfig()
+	retry.InitialInterval = time.Millisecond
+	retry.MaxInterval = 10 * time.Millisecond
+	return Queue{Enabled: true, QueueSize: 10, RetryOnFailure: retry}
+}
+
+func newTestTraces(name string) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(name)
+	return traces
+}
+
+func spanName(td ptrace.Traces) string {
+	return td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name()
+}
+
+// blockingStorageClient blocks the removal of items until unblock is closed
+type blockingStorageClient struct {
+	*memoryStorageClient
+	unblock chan struct{}
+}
+
+func (c *blockingStorageClient) Batch(ctx context.Context, ops ...storage.Operation) error {
+	for _, op := range ops {
+		if op.Type == storage.Delete {
+			<-c.unblock
+			break
+		}
+	}
+	return c.memoryStorageClient.Batch(ctx, ops...)
+}
@@ -2,58 +2,171 @@ package gitlabreceiver
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"slices"
+	"strconv"
 	"sync"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pipeline"
 	"go.opentelemetry.io/collector/receiver"
 	"go.uber.org/zap"
 )
 
 type gitlabReceiver struct {
-	host               component.Host
-	cancel             context.CancelFunc
-	cfg                *Config
-	logger             *zap.Logger
-	nextTracesConsumer consumer.Traces
-	httpServer         *http.Server
-	settings           *receiver.Settings
-	shutdownWG         sync.WaitGroup
-	glResource         gitlabResource
+	host                component.Host
+	cancel              context.CancelFunc
+	cfg                 *Config
+	signingKey          []byte
+	logger              *zap.Logger
+	nextTracesConsumer  consumer.Traces
+	nextMetricsConsumer consumer.Metrics
+	nextLogsConsumer    consumer.Logs
+	httpServer          *http.Server
+	settings            *receiver.Settings
+	shutdownWG          sync.WaitGroup
+	pipelines           *pipelineStore
+	refs                *refFilter
+	metricsRefs         *refFilter
+	projects            *projectFilter
+	dora                *doraTracker
+	tracesQueue         *tracesQueue
+	deliveries          *deliveries
+	telemetry           *receiverTelemetry
+	backfiller          *backfiller
+	reconciler          *reconciler
+	testReports         *testReports
 }
 
-func newGitlabReceiver(cfg component.Config, s receiver.Settings) *gitlabReceiver {
-	return &gitlabReceiver{
-		logger:   s.Logger,
-		settings: &s,
-		cfg:      cfg.(*Config),
+func newGitlabReceiver(cfg component.Config, s receiver.Settings) (*gitlabReceiver, error) {
+	telemetry, err := newReceiverTelemetry(s)
+	if err != nil {
+		return nil, err
+	}
+
+	glRcvr := &gitlabReceiver{
+		logger:    s.Logger,
+		settings:  &s,
+		cfg:       cfg.(*Config),
+		pipelines: newPipelineStore(),
+		telemetry: telemetry,
+	}
+	glRcvr.refs, err = newRefFilter(glRcvr.cfg.Traces.Refs, glRcvr.cfg.Traces.ExcludeRefs)
+	if err != nil {
+		return nil, err
+	}
+	glRcvr.metricsRefs, err = newRefFilter(glRcvr.cfg.Metrics.Refs, glRcvr.cfg.Metrics.ExcludeRefs)
+	if err != nil {
+		return nil, err
+	}
+	glRcvr.projects, err = newProjectFilter(glRcvr.cfg.Projects.Include, glRcvr.cfg.Projects.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	if glRcvr.cfg.SigningKey != "" {
+		glRcvr.signingKey, err = decodeSigningKey(string(glRcvr.cfg.SigningKey))
+		if err != nil {
+			return nil, err
+		}
 	}
+	if glRcvr.cfg.Dedup.Enabled {
+		glRcvr.deliveries = newDeliveries(glRcvr.cfg.Dedup)
+	}
+	if glRcvr.cfg.Metrics.Dora.Enabled {
+		glRcvr.dora = newDoraTracker(glRcvr.cfg.Metrics.Dora, s.Logger)
+	}
+	return glRcvr, nil
 }
 
 func (glRcvr *gitlabReceiver) Start(ctx context.Context, host component.Host) error {
 	glRcvr.host = host
 	ctx, glRcvr.cancel = context.WithCancel(ctx)
 
-	go func() {
-		err := glRcvr.startHTTPServer(ctx, host)
+	if glRcvr.dora != nil && glRcvr.cfg.Metrics.Dora.StorageID != nil {
+		client, err := getStorageClient(ctx, host, *glRcvr.cfg.Metrics.Dora.StorageID, glRcvr.settings.ID, doraStorageName)
 		if err != nil {
-			glRcvr.logger.Panic("Unable to start", zap.Error(err))
+			return err
 		}
-	}()
-	return nil
+		glRcvr.dora.client = client
+	}
+
+	if glRcvr.cfg.Traces.Queue.Enabled && glRcvr.nextTracesConsumer != nil {
+		client, err := getStorageClient(ctx, host, *glRcvr.cfg.Traces.Queue.StorageID, glRcvr.settings.ID, tracesQueueStorageName)
+		if err != nil {
+			return err
+		}
+		glRcvr.tracesQueue = newTracesQueue(glRcvr.cfg.Traces.Queue, client, glRcvr.nextTracesConsumer, glRcvr.logger)
+		if err := glRcvr.tracesQueue.start(ctx); err != nil {
+			return err
+		}
+	}
+
+	if glRcvr.cfg.usesAPI() && glRcvr.nextTracesConsumer != nil {
+		api, err := newGitlabClient(ctx, glRcvr.cfg.API, host, glRcvr.settings.TelemetrySettings)
+		if err != nil {
+			return err
+		}
+		// Test reports are started first, because the backfill and the reconciliation export pipelines right away
+		if glRcvr.cfg.Traces.TestReports.Enabled {
+			glRcvr.testReports = newTestReports(glRcvr.cfg.Traces.TestReports, api, glRcvr.exportTraces, glRcvr.logger)
+			glRcvr.testReports.start()
+		}
+		if glRcvr.cfg.Traces.Backfill.Enabled {
+			if err := glRcvr.startBackfill(ctx, host, api); err != nil {
+				return err
+			}
+		}
+		if glRcvr.cfg.Traces.Reconcile.Enabled {
+			glRcvr.reconciler = newReconciler(glRcvr.cfg.Traces.Reconcile, glRcvr.cfg.API.Projects, api, glRcvr.isMissing, glRcvr.handlePipelineTraces, glRcvr.logger)
+			glRcvr.reconciler.start()
+		}
+	}
+
+	// The listener is created synchronously, the shared receiver of all signals can be shut down right after the start
+	return glRcvr.startHTTPServer(ctx, host)
 }
 
 func (glRcvr *gitlabReceiver) Shutdown(ctx context.Context) error {
+	var err error
 	if glRcvr.httpServer != nil {
-		err := glRcvr.httpServer.Shutdown(ctx)
-		return err
+		err = glRcvr.httpServer.Shutdown(ctx)
 	}
 	glRcvr.shutdownWG.Wait()
+	if glRcvr.dora != nil {
+		err = errors.Join(err, glRcvr.dora.close(ctx))
+	}
+	// The backfill, the reconciliation and the test reports are stopped before the queue, because they might still export traces
+	if glRcvr.backfiller != nil {
+		err = errors.Join(err, glRcvr.backfiller.shutdown(ctx))
+	}
+	if glRcvr.reconciler != nil {
+		glRcvr.reconciler.shutdown()
+	}
+	if glRcvr.testReports != nil {
+		glRcvr.testReports.shutdown()
+	}
+	if glRcvr.tracesQueue != nil {
+		err = errors.Join(err, glRcvr.tracesQueue.shutdown(ctx))
+	}
+	return err
+}
+
+func (glRcvr *gitlabReceiver) startBackfill(ctx context.Context, host component.Host, api *gitlabClient) error {
+	var err error
+	glRcvr.backfiller = newBackfiller(glRcvr.cfg.Traces.Backfill, glRcvr.cfg.API.Projects, api, glRcvr.handlePipelineTraces, glRcvr.logger)
+	if glRcvr.cfg.Traces.Backfill.StorageID != nil {
+		glRcvr.backfiller.client, err = getStorageClient(ctx, host, *glRcvr.cfg.Traces.Backfill.StorageID, glRcvr.settings.ID, backfillStorageName)
+		if err != nil {
+			return err
+		}
+	}
+	glRcvr.backfiller.start()
 	return nil
 }
 
@@ -70,9 +183,20 @@ func (glRcvr *gitlabReceiver) startHTTPServer(ctx context.Context, host componen
 		return err
 	}
 
+	// Signals can share the same url path, in this case every event creates telemetry for all of them
+	signals := make(map[string][]pipeline.Signal)
 	if glRcvr.nextTracesConsumer != nil {
-		httpMux.HandleFunc(glRcvr.cfg.Traces.UrlPath, func(resp http.ResponseWriter, req *http.Request) {
-			glRcvr.handleTraces(ctx, resp, req)
+		signals[glRcvr.cfg.Traces.UrlPath] = append(signals[glRcvr.cfg.Traces.UrlPath], pipeline.SignalTraces)
+	}
+	if glRcvr.nextMetricsConsumer != nil {
+		signals[glRcvr.cfg.Metrics.UrlPath] = append(signals[glRcvr.cfg.Metrics.UrlPath], pipeline.SignalMetrics)
+	}
+	if glRcvr.nextLogsConsumer != nil {
+		signals[glRcvr.cfg.Logs.UrlPath] = append(signals[glRcvr.cfg.Logs.UrlPath], pipeline.SignalLogs)
+	}
+	for urlPath, s := range signals {
+		httpMux.HandleFunc(urlPath, func(resp http.ResponseWriter, req *http.Request) {
+			glRcvr.handleEvent(ctx, resp, req, s)
 		})
 	}
 
@@ -89,26 +213,140 @@ func (glRcvr *gitlabReceiver) startHTTPServer(ctx context.Context, host componen
 	return nil
 }
 
-func (glRcvr *gitlabReceiver) handleTraces(ctx context.Context, w http.ResponseWriter, req *http.Request) {
-	err := glRcvr.validateReq(req)
+func (glRcvr *gitlabReceiver) handleEvent(ctx context.Context, rw http.ResponseWriter, req *http.Request, signals []pipeline.Signal) {
+	w := &statusRecorder{ResponseWriter: rw, statusCode: http.StatusOK}
+	// The raw header isn't authenticated and could have any value, only the headers of known hooks are recorded
+	event := knownEventHeader(req.Header.Get(gitlabEventHeader))
+	defer func() {
+		glRcvr.telemetry.recordWebhook(ctx, event, w.statusCode)
+	}()
+
+	err := glRcvr.validateToken(req)
+	if err != nil {
+		glRcvr.telemetry.recordValidationFailure(ctx, validationReasonToken)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		glRcvr.logger.Warn("Unauthorized request - Secret token validation failed", zap.Error(err), zap.String("remote_addr", req.RemoteAddr))
+		return
+	}
+
+	err = glRcvr.validateReq(req)
 	if err != nil {
+		glRcvr.telemetry.recordValidationFailure(ctx, validationReasonRequest)
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		glRcvr.logger.Error("Invalid request - Validation failed", zap.Error(err))
 		return
 	}
 
-	glEvent, err := glRcvr.unmarshalReq(req)
+	// The body is read once to verify the signature and to decode the event, the server limits it to max_request_body_size
+	body, err := io.ReadAll(req.Body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		glRcvr.telemetry.recordValidationFailure(ctx, validationReasonBodySize)
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		glRcvr.logger.Warn("Invalid request - Body exceeds the limit", zap.Int64("limit", maxBytesErr.Limit), zap.String("remote_addr", req.RemoteAddr))
+		return
+	}
+	if err != nil {
+		glRcvr.telemetry.recordValidationFailure(ctx, validationReasonRequest)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		glRcvr.logger.Error("Invalid request - Unable to read the body", zap.Error(err))
+		return
+	}
+
+	err = glRcvr.verifyReq(req.Header, body)
+	if err != nil {
+		glRcvr.telemetry.recordValidationFailure(ctx, validationReasonSignature)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		glRcvr.logger.Warn("Unauthorized request - Signature verification failed", zap.Error(err), zap.String("remote_addr", req.RemoteAddr))
+		return
+	}
+
+	handler, err := getEventHandler(req, body)
+	if err == nil && !handler.supports(signals) {
+		err = fmt.Errorf("%w: %s for %v", errUnsupportedEvent, handler.header, signals)
+	}
+	if errors.Is(err, errUnsupportedEvent) {
+		glRcvr.logger.Debug("Received event type is not supported", zap.Error(err))
+		w.WriteHeader(http.StatusAccepted)
+		_, err = w.Write([]byte("Event type not supported"))
+		if err != nil {
+			glRcvr.logger.Error("Unable to send response", zap.Error(err))
+		}
+		return
+	}
+	if err != nil {
+		glRcvr.telemetry.recordDecodeFailure(ctx, event)
+		http.Error(w, "Unable to handle the request", http.StatusBadRequest)
+		glRcvr.logger.Error("Unable to determine the event type", zap.Error(err))
+		return
+	}
+
+	glEvent, err := handler.decode(body)
 	if err != nil {
+		glRcvr.telemetry.recordDecodeFailure(ctx, handler.header)
 		http.Error(w, "Unable to handle the request", http.StatusBadRequest)
 		glRcvr.logger.Error("Error unmarshalling the request", zap.Error(err))
 		return
 	}
 
-	glPipelineEvent := glEvent.(*glPipelineEvent)
-	glRcvr.glResource = glPipelineEvent
+	if !glRcvr.isProjectExported(glEvent) {
+		glRcvr.logger.Debug("Received project is not configured to be exported", zap.String("event", handler.header))
+		glRcvr.telemetry.recordFiltered(ctx, filterReasonProject)
+		_, err = w.Write([]byte("Not configured to be exported"))
+		if err != nil {
+			glRcvr.logger.Error("Unable to send response", zap.Error(err))
+		}
+		return
+	}
+
+	var deliveryKey string
+	if glRcvr.deliveries != nil {
+		deliveryKey = deliveryKeyOf(req, glEvent)
+	}
 
-	if len(glRcvr.cfg.Traces.Refs) > 0 && !slices.Contains(glRcvr.cfg.Traces.Refs, glPipelineEvent.Pipeline.Ref) {
-		glRcvr.logger.Info("Received ref is not configured to be exported.", zap.String("Pipeline", glPipelineEvent.Pipeline.Url), zap.String("Ref", glPipelineEvent.Pipeline.Ref))
+	// The event is handled for every signal, even if one of them fails. An event is only considered as not exported if none of the signals exported it.
+	// Every signal claims the delivery before it is handled, so that a concurrent delivery of the same event isn't handled twice and a redelivery
+	// after a partial failure only handles the signals which failed.
+	exported, handled, inFlight := false, false, false
+	var exportErr error
+	for _, signal := range signals {
+		handle := handler.signal(signal)
+		if handle == nil {
+			continue
+		}
+		key := signalDeliveryKey(deliveryKey, signal)
+		if state, ok := glRcvr.deliveries.claim(key); !ok {
+			glRcvr.logger.Debug("Received event was already handled", zap.String("key", key))
+			inFlight = inFlight || state == deliveryInFlight
+			continue
+		}
+		handled = true
+		err = handle(glRcvr, ctx, glEvent)
+		if err != nil && !errors.Is(err, errNotExported) {
+			glRcvr.deliveries.release(key)
+			glRcvr.logger.Error(fmt.Sprintf("Unable to export the %s", signal), zap.Error(err))
+			exportErr = errors.Join(exportErr, err)
+			continue
+		}
+		glRcvr.deliveries.setHandled(key)
+		exported = exported || err == nil
+	}
+	if exportErr != nil {
+		http.Error(w, "Unable to export the event", http.StatusInternalServerError)
+		return
+	}
+	if inFlight {
+		http.Error(w, "Event is already being handled", http.StatusConflict)
+		return
+	}
+	if !handled {
+		_, err = w.Write([]byte("Already handled"))
+		if err != nil {
+			glRcvr.logger.Error("Unable to send response", zap.Error(err))
+		}
+		return
+	}
+	if !exported {
 		_, err = w.Write([]byte("Not configured to be exported"))
 		if err != nil {
 			glRcvr.logger.Error("Unable to send response", zap.Error(err))
@@ -116,21 +354,298 @@ func (glRcvr *gitlabReceiver) handleTraces(ctx context.Context, w http.ResponseW
 		return
 	}
 
+	_, err = w.Write([]byte("OK"))
+	if err != nil {
+		glRcvr.logger.Error("Unable to send response", zap.Error(err))
+	}
+}
+
+func (glRcvr *gitlabReceiver) handlePipelineTraces(ctx context.Context, p *glPipelineEvent) error {
+	// webhooks are filtered by their project before, the pipelines of the backfill and the reconciliation are not
+	if !glRcvr.isProjectExported(p) {
+		glRcvr.logger.Debug("Received project is not configured to be exported", zap.String("Pipeline", p.Pipeline.Url))
+		glRcvr.telemetry.recordFiltered(ctx, filterReasonProject)
+		return errNotExported
+	}
+	if !glRcvr.isRefExported(p) {
+		glRcvr.logger.Info("Received ref is not configured to be exported.", zap.String("Pipeline", p.Pipeline.Url), zap.String("Ref", p.Pipeline.Ref))
+		glRcvr.telemetry.recordFiltered(ctx, filterReasonRef)
+		return errNotExported
+	}
+
 	// we only want to export the root span if the pipeline is finished
 	// finished date and running status would inidcate some sort of retry/restart which we want to export once it is finished in a separate trace
-	if glPipelineEvent.Pipeline.FinishedAt != "" && glPipelineEvent.Pipeline.Status != "running" {
-		err = glRcvr.exportTraces(ctx)
+	if p.Pipeline.FinishedAt == "" || p.Pipeline.Status == "running" {
+		return nil
+	}
+
+	p.jobEvents = glRcvr.pipelines.getJobEvents(p.Pipeline.Id)
+	p.deployments = glRcvr.pipelines.getDeployments(p.jobIds())
+	p.stageSpans = glRcvr.cfg.Traces.StageSpans
+	p.jobPhases = glRcvr.cfg.Traces.JobPhases
+	p.stableTraceId = glRcvr.cfg.Traces.StableTraceId
+	p.attempts = glRcvr.pipelines.getAttempts(p.Pipeline.Id)
+	p.downstream = glRcvr.pipelines.getDownstream(p.Pipeline.Id)
+	p.upstream = glRcvr.upstreamLink(p)
+	err := glRcvr.exportTraces(ctx, p)
+	if err != nil {
+		return err
+	}
+	glRcvr.setExported(ctx, p)
+	if glRcvr.testReports != nil {
+		glRcvr.testReports.add(p)
+	}
+
+	return nil
+}
+
+// Job events are stored until the trace of their pipeline is created. If the pipeline trace was already exported,
+// jobs which aren't part of the exported trace yet are exported as part of the existing pipeline trace.
+func (glRcvr *gitlabReceiver) handleJobTraces(ctx context.Context, e *glJobEvent) error {
+	exported, ok := glRcvr.pipelines.addJobEvent(e)
+	if !ok || e.FinishedAt == "" {
+		return nil
+	}
+	return glRcvr.exportJob(ctx, exported, e)
+}
+
+// Exports a finished job as part of the already exported pipeline trace
+func (glRcvr *gitlabReceiver) exportJob(ctx context.Context, exported exportedPipeline, e *glJobEvent) error {
+	if _, ok := exported.jobIds[e.Id]; ok {
+		return nil
+	}
+
+	// The stored job event is shared, therefore the details are set on a copy
+	jobEvent := *e
+	jobEvent.setDetails(e.Project.Url, fmt.Sprintf("%s/pipelines/%s", e.Project.Url, strconv.Itoa(e.PipelineId)))
+	jobEvent.traceId = exported.traceId
+	jobEvent.jobPhases = glRcvr.cfg.Traces.JobPhases
+	jobEvent.parentSpanId = exported.rootSpanId
+	if stageSpanId, ok := exported.stageSpanIds[e.Stage]; ok {
+		jobEvent.parentSpanId = stageSpanId
+	}
+	spanId, err := exported.jobSpanId(e.Id)
+	if err != nil {
+		return err
+	}
+	jobEvent.spanId = spanId
+
+	err = glRcvr.exportTraces(ctx, &jobEvent)
+	if err != nil {
+		return err
+	}
+	glRcvr.pipelines.addExportedJob(e.PipelineId, e.Id)
+
+	return nil
+}
+
+// Job events which were received while the pipeline was exported are exported on their own afterwards
+func (glRcvr *gitlabReceiver) setExported(ctx context.Context, p *glPipelineEvent) {
+	traceId, rootSpanId, err := p.traceContext()
+	if err != nil {
+		glRcvr.logger.Error("Unable to determine the trace context of the exported pipeline", zap.Error(err))
+		return
+	}
+
+	jobIds := make(map[int]struct{}, len(p.Jobs)+len(p.jobEvents))
+	for _, j := range p.Jobs {
+		if j.FinishedAt != "" {
+			jobIds[j.Id] = struct{}{}
+		}
+	}
+	for _, e := range p.jobEvents {
+		if e.FinishedAt != "" {
+			jobIds[e.Id] = struct{}{}
+		}
+	}
+
+	deploymentIds := make(map[int]struct{}, len(p.deployments))
+	for _, d := range p.deployments {
+		if d.isFinished() {
+			deploymentIds[d.Id] = struct{}{}
+		}
+	}
+
+	stageSpanIds := make(map[string][8]byte)
+	if p.stageSpans {
+		for _, js := range p.finishedJobs() {
+			stageSpanIds[js.job.Stage], err = p.stageSpanId(js.job.Stage)
+			if err != nil {
+				glRcvr.logger.Error("Unable to determine the stage span id of the exported pipeline", zap.Error(err))
+				return
+			}
+		}
+	}
+
+	glRcvr.pipelines.addAttempt(p.Pipeline.Id, pipelineAttempt{
+		attempt:    p.attempt(),
+		finishedAt: p.Pipeline.FinishedAt,
+		traceId:    traceId,
+		rootSpanId: rootSpanId,
+	})
+	if p.isDownstream() {
+		glRcvr.pipelines.addDownstream(p.ParentPipeline.Id, pipelineLink{
+			pipelineId:  p.Pipeline.Id,
+			bridgeJobId: p.ParentPipeline.JobId,
+			traceId:     traceId,
+			spanId:      rootSpanId,
+		})
+	}
+	if key, ok := p.mergeRequestKey(); ok {
+		glRcvr.pipelines.addMergeRequestPipeline(key, pipelineLink{
+			pipelineId: p.Pipeline.Id,
+			traceId:    traceId,
+			spanId:     rootSpanId,
+		})
+	}
+	exported := exportedPipeline{
+		sha:           p.Pipeline.Sha,
+		pipelineId:    p.Pipeline.Id,
+		hashTime:      p.hashTime(),
+		finishedAt:    p.Pipeline.FinishedAt,
+		traceId:       traceId,
+		rootSpanId:    rootSpanId,
+		stageSpanIds:  stageSpanIds,
+		jobIds:        jobIds,
+		deploymentIds: deploymentIds,
+	}
+	for _, e := range glRcvr.pipelines.setExported(p.Pipeline.Id, exported, p.jobIds()) {
+		err := glRcvr.exportJob(ctx, exported, e)
 		if err != nil {
-			http.Error(w, "Unable to export the trace", http.StatusInternalServerError)
-			glRcvr.logger.Error("Unable to export the trace", zap.Error(err))
-			return
+			glRcvr.logger.Error("Unable to export the job which finished while its pipeline was exported", zap.Error(err), zap.Int("Job", e.Id))
 		}
 	}
+}
 
-	_, err = w.Write([]byte("OK"))
+// The project filter is applied to all signals, before the event is translated, and to the pipelines of the API
+func (glRcvr *gitlabReceiver) isProjectExported(event gitlabResource) bool {
+	e, ok := event.(projectEvent)
+	if !ok {
+		return true
+	}
+	return glRcvr.projects.matches(e.project())
+}
+
+func (glRcvr *gitlabReceiver) isRefExported(p *glPipelineEvent) bool {
+	return glRcvr.refs.matches(p.refs())
+}
+
+// Lifecycle events of merge requests are exported into the trace of the merge request. The start of a lifecycle span is
+// the previous lifecycle event of the merge request, therefore the times of the exported events are stored.
+func (glRcvr *gitlabReceiver) handleMergeRequestTraces(ctx context.Context, mr *glMergeRequestEvent) error {
+	if !mr.isLifecycleEvent() {
+		return errNotExported
+	}
+	eventTime, err := mr.eventTime()
 	if err != nil {
-		glRcvr.logger.Error("Unable to send response", zap.Error(err))
+		return err
+	}
+
+	state := glRcvr.pipelines.getMergeRequest(mr.key())
+	for _, t := range state.eventTimes {
+		if t < eventTime {
+			mr.previousEventAt = t
+		}
+	}
+	mr.pipelines = state.pipelines
+	err = glRcvr.exportTraces(ctx, mr)
+	if err != nil {
+		return err
+	}
+	glRcvr.pipelines.addMergeRequestEvent(mr.key(), eventTime)
+
+	return nil
+}
+
+// A finished pipeline is missing if its trace wasn't exported yet. Pipelines of refs which aren't exported are never missing.
+// The finished time is compared as well, because the trace id of retried pipelines is the same with stable trace ids.
+func (glRcvr *gitlabReceiver) isMissing(p *glPipelineEvent) bool {
+	if !glRcvr.isProjectExported(p) || !glRcvr.isRefExported(p) {
+		return false
+	}
+	if p.Pipeline.FinishedAt == "" || p.Pipeline.Status == "running" {
+		return false
+	}
+
+	p.stableTraceId = glRcvr.cfg.Traces.StableTraceId
+	traceId, _, err := p.traceContext()
+	if err != nil {
+		return true
+	}
+	exported, ok := glRcvr.pipelines.getExported(p.Pipeline.Id)
+	return !ok || exported.traceId != traceId || exported.finishedAt != p.Pipeline.FinishedAt
+}
+
+// If the upstream pipeline was already exported, the downstream pipeline links to the span of its bridge job.
+// Bridge jobs aren't part of the pipeline events, in this case the root span of the upstream pipeline is linked.
+func (glRcvr *gitlabReceiver) upstreamLink(p *glPipelineEvent) *pipelineLink {
+	if !p.isDownstream() {
+		return nil
+	}
+	upstream, ok := glRcvr.pipelines.getExported(p.ParentPipeline.Id)
+	if !ok {
+		return nil
+	}
+
+	spanId := upstream.rootSpanId
+	if _, ok := upstream.jobIds[p.ParentPipeline.JobId]; ok {
+		bridgeSpanId, err := upstream.jobSpanId(p.ParentPipeline.JobId)
+		if err == nil {
+			spanId = bridgeSpanId
+		}
+	}
+	return &pipelineLink{
+		pipelineId:  p.ParentPipeline.Id,
+		bridgeJobId: p.ParentPipeline.JobId,
+		traceId:     upstream.traceId,
+		spanId:      spanId,
+	}
+}
+
+// Deployments are stored until the trace of the pipeline of their deployable job is created. If the pipeline trace was
+// already exported, the deployment is exported as part of the existing pipeline trace.
+func (glRcvr *gitlabReceiver) handleDeploymentTraces(ctx context.Context, d *glDeploymentEvent) error {
+	glRcvr.pipelines.addDeployment(d)
+	if !d.isFinished() {
+		return nil
+	}
+
+	var err error
+	pipelineId, ok := glRcvr.pipelines.getJobPipeline(d.DeployableId)
+	if !ok {
+		return nil
+	}
+	exported, ok := glRcvr.pipelines.getExported(pipelineId)
+	if !ok {
+		return nil
+	}
+	if _, ok := exported.deploymentIds[d.Id]; ok {
+		return nil
+	}
+
+	// The stored deployment event is shared, therefore the trace context is set on a copy.
+	// The deployment is a child of its job span if the job is part of the exported trace.
+	deployment := *d
+	deployment.traceId = exported.traceId
+	deployment.parentSpanId = exported.rootSpanId
+	if _, ok := exported.jobIds[d.DeployableId]; ok {
+		deployment.parentSpanId, err = exported.jobSpanId(d.DeployableId)
+		if err != nil {
+			return err
+		}
+	}
+	deployment.spanId, err = exported.deploymentSpanId(d.Id)
+	if err != nil {
+		return err
 	}
+
+	err = glRcvr.exportTraces(ctx, &deployment)
+	if err != nil {
+		return err
+	}
+	glRcvr.pipelines.addExportedDeployment(pipelineId, d.Id)
+
+	return nil
 }
 
 func (glRcvr *gitlabReceiver) validateReq(req *http.Request) error {
@@ -142,37 +657,122 @@ func (glRcvr *gitlabReceiver) validateReq(req *http.Request) error {
 		return errors.New("request has unsupported content type")
 	}
 
-	if req.Header.Get("X-Gitlab-Event") != "Pipeline Hook" {
+	if req.Header.Get(gitlabEventHeader) == "" {
 		return errors.New("invalid request header")
 	}
 
 	return nil
 }
 
-// ToDo: Refactor the unmarshal process when there is more than one possible event
-func (glRcvr *gitlabReceiver) unmarshalReq(req *http.Request) (gitlabResource, error) {
-	var glEvent gitlabResource
-	var err error
-	if req.Header.Get("X-Gitlab-Event") == "Pipeline Hook" {
-		glEvent, err = decode[*glPipelineEvent](req)
+// The token is compared in constant time against every configured token to not leak any information about the configured secrets
+func (glRcvr *gitlabReceiver) validateToken(req *http.Request) error {
+	tokens := glRcvr.cfg.secretTokens()
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	token := req.Header.Get("X-Gitlab-Token")
+	if token == "" {
+		return errors.New("missing X-Gitlab-Token header")
+	}
+
+	match := 0
+	for _, t := range tokens {
+		match |= subtle.ConstantTimeCompare([]byte(token), []byte(t))
+	}
+	if match != 1 {
+		return errors.New("invalid X-Gitlab-Token header")
+	}
+
+	return nil
+}
+
+// The signature is verified over the raw body
+func (glRcvr *gitlabReceiver) verifyReq(header http.Header, body []byte) error {
+	if glRcvr.signingKey == nil {
+		return nil
+	}
+	return verifySignature(glRcvr.signingKey, glRcvr.cfg.SignatureTolerance, header, body, time.Now())
+}
+
+func (glRcvr *gitlabReceiver) handlePipelineMetrics(ctx context.Context, p *glPipelineEvent) error {
+	if !glRcvr.metricsRefs.matches(p.refs()) {
+		glRcvr.telemetry.recordFiltered(ctx, filterReasonRef)
+		return errNotExported
+	}
+
+	// like traces, metrics are only created for finished pipelines
+	if p.Pipeline.FinishedAt == "" || p.Pipeline.Status == "running" {
+		return nil
+	}
+
+	metrics, err := p.newMetrics(glRcvr.cfg.Metrics)
+	if err != nil {
+		return err
+	}
+	if glRcvr.dora != nil {
+		doraMetrics, err := glRcvr.dora.track(ctx, p)
 		if err != nil {
-			return nil, errors.New("unable to read the body: " + err.Error())
+			return err
 		}
+		doraMetrics.ResourceMetrics().MoveAndAppendTo(metrics.ResourceMetrics())
+	}
+	if metrics.DataPointCount() == 0 {
+		return nil
 	}
 
-	return glEvent, nil
+	return glRcvr.telemetry.consumeMetrics(ctx, metrics, glRcvr.nextMetricsConsumer.ConsumeMetrics)
 }
 
-func (glRcvr *gitlabReceiver) exportTraces(ctx context.Context) error {
-	traces, err := glRcvr.glResource.newTrace()
+func (glRcvr *gitlabReceiver) handlePipelineLogs(ctx context.Context, p *glPipelineEvent) error {
+	// The log records of finished pipelines carry the trace context of the pipeline trace
+	p.stableTraceId = glRcvr.cfg.Traces.StableTraceId
+	logs, err := p.newLogs()
 	if err != nil {
 		return err
 	}
+	return glRcvr.telemetry.consumeLogs(ctx, logs, glRcvr.nextLogsConsumer.ConsumeLogs)
+}
 
-	err = glRcvr.nextTracesConsumer.ConsumeTraces(ctx, *traces)
+func (glRcvr *gitlabReceiver) handleJobLogs(ctx context.Context, e *glJobEvent) error {
+	// Like for job spans, the details are set on a copy because the job event is also stored for the pipeline trace
+	jobEvent := *e
+	jobEvent.setDetails(e.Project.Url, fmt.Sprintf("%s/pipelines/%s", e.Project.Url, strconv.Itoa(e.PipelineId)))
+
+	logs, err := jobEvent.newLogs()
 	if err != nil {
 		return err
 	}
+	return glRcvr.telemetry.consumeLogs(ctx, logs, glRcvr.nextLogsConsumer.ConsumeLogs)
+}
 
-	return nil
+func (glRcvr *gitlabReceiver) handleDeploymentLogs(ctx context.Context, d *glDeploymentEvent) error {
+	logs, err := d.newLogs()
+	if err != nil {
+		return err
+	}
+	return glRcvr.telemetry.consumeLogs(ctx, logs, glRcvr.nextLogsConsumer.ConsumeLogs)
+}
+
+func (glRcvr *gitlabReceiver) handleMergeRequestLogs(ctx context.Context, mr *glMergeRequestEvent) error {
+	logs, err := mr.newLogs()
+	if err != nil {
+		return err
+	}
+	return glRcvr.telemetry.consumeLogs(ctx, logs, glRcvr.nextLogsConsumer.ConsumeLogs)
+}
+
+// The event is passed along with the request, the receiver must not keep any state of a single request because requests are handled concurrently
+func (glRcvr *gitlabReceiver) exportTraces(ctx context.Context, glRes gitlabResource) error {
+	traces, err := glRes.newTrace()
+	if err != nil {
+		return err
+	}
+
+	// With the queue the traces are exported in the background, the webhook is acknowledged once they are persisted
+	if glRcvr.tracesQueue != nil {
+		return glRcvr.telemetry.consumeTraces(ctx, *traces, glRcvr.tracesQueue.enqueue)
+	}
+
+	return glRcvr.telemetry.consumeTraces(ctx, *traces, glRcvr.nextTracesConsumer.ConsumeTraces)
 }
@@ -3,19 +3,30 @@ package gitlabreceiver
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.uber.org/zap"
 )
 
 const (
@@ -75,7 +86,7 @@ func TestGitlabReceiverHttpServer(t *testing.T) {
 	require.NoError(t, err, "error finding an available port")
 	cfg.Endpoint = fmt.Sprintf("localhost:%s", p)
 
-	glRcvr := newGitlabReceiver(cfg, s)
+	glRcvr := newTestReceiver(t, cfg, s)
 	glRcvr.nextTracesConsumer = consumertest.NewNop()
 
 	require.NoError(t, err, "Failed to create traces receiver")
@@ -85,12 +96,17 @@ func TestGitlabReceiverHttpServer(t *testing.T) {
 	})
 
 	tests := []struct {
-		name       string
-		httpMethod string
-		reqBody    []byte
-		resBody    string
-		statusCode int
-		refs       []string
+		name         string
+		httpMethod   string
+		eventType    string
+		reqBody      []byte
+		resBody      string
+		statusCode   int
+		refs         []string
+		secretTokens []configopaque.String
+		token        string
+		signingKey   configopaque.String
+		signature    bool
 	}{
 		{
 			name:       "unsupported httpMethod",
@@ -110,6 +126,20 @@ func TestGitlabReceiverHttpServer(t *testing.T) {
 			reqBody:    []byte(pipelineCreatedJobPending),
 			resBody:    "OK",
 			statusCode: http.StatusOK,
+		}, {
+			name:       "unsupported event type",
+			httpMethod: http.MethodPost,
+			eventType:  "Push Hook",
+			reqBody:    []byte(`{"object_kind": "push"}`),
+			resBody:    "Event type not supported",
+			statusCode: http.StatusAccepted,
+		}, {
+			name:       "missing event type",
+			httpMethod: http.MethodPost,
+			eventType:  "-",
+			reqBody:    []byte(pipelineCreatedJobPending),
+			resBody:    "Invalid request\n",
+			statusCode: http.StatusBadRequest,
 		}, {
 			name:       "valid request but not to be exported ref",
 			httpMethod: http.MethodPost,
@@ -131,16 +161,85 @@ func TestGitlabReceiverHttpServer(t *testing.T) {
 			resBody:    "OK",
 			statusCode: http.StatusOK,
 			refs:       []string{"xyz"},
+		}, {
+			name:         "valid request and matching secret token",
+			httpMethod:   http.MethodPost,
+			reqBody:      []byte(pipelineOnFeatureBranch),
+			resBody:      "OK",
+			statusCode:   http.StatusOK,
+			secretTokens: []configopaque.String{"secret"},
+			token:        "secret",
+		}, {
+			name:         "valid request and rotated secret token",
+			httpMethod:   http.MethodPost,
+			reqBody:      []byte(pipelineOnFeatureBranch),
+			resBody:      "OK",
+			statusCode:   http.StatusOK,
+			secretTokens: []configopaque.String{"old-secret", "new-secret"},
+			token:        "new-secret",
+		}, {
+			name:         "valid request and invalid secret token",
+			httpMethod:   http.MethodPost,
+			reqBody:      []byte(pipelineOnFeatureBranch),
+			resBody:      "Unauthorized\n",
+			statusCode:   http.StatusUnauthorized,
+			secretTokens: []configopaque.String{"secret"},
+			token:        "wrong-secret",
+		}, {
+			name:         "valid request and missing secret token",
+			httpMethod:   http.MethodPost,
+			reqBody:      []byte(pipelineOnFeatureBranch),
+			resBody:      "Unauthorized\n",
+			statusCode:   http.StatusUnauthorized,
+			secretTokens: []configopaque.String{"secret"},
+		}, {
+			name:       "valid request and valid signature",
+			httpMethod: http.MethodPost,
+			reqBody:    []byte(pipelineOnFeatureBranch),
+			resBody:    "OK",
+			statusCode: http.StatusOK,
+			signingKey: testSigningKey,
+			signature:  true,
+		}, {
+			name:       "valid request and missing signature",
+			httpMethod: http.MethodPost,
+			reqBody:    []byte(pipelineOnFeatureBranch),
+			resBody:    "Unauthorized\n",
+			statusCode: http.StatusUnauthorized,
+			signingKey: testSigningKey,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			glRcvr.cfg.Traces.Refs = tc.refs
+			glRcvr.refs, err = newRefFilter(tc.refs, nil)
+			require.NoError(t, err)
+			glRcvr.cfg.SecretTokens = tc.secretTokens
+			glRcvr.signingKey = nil
+			if tc.signingKey != "" {
+				glRcvr.signingKey, err = decodeSigningKey(string(tc.signingKey))
+				require.NoError(t, err, "Unable to decode the signing key")
+			}
 
 			request, err := http.NewRequest(tc.httpMethod, fmt.Sprintf("http://%s%s", cfg.Endpoint, cfg.Traces.UrlPath), bytes.NewReader(tc.reqBody))
 			request.Header.Set("Content-Type", "application/json")
-			request.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+			switch tc.eventType {
+			case "":
+				request.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+			case "-":
+			default:
+				request.Header.Set("X-Gitlab-Event", tc.eventType)
+			}
+			if tc.token != "" {
+				request.Header.Set("X-Gitlab-Token", tc.token)
+			}
+			if tc.signature {
+				now := time.Now()
+				request.Header.Set(webhookIdHeader, "msg_1")
+				request.Header.Set(webhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+				request.Header.Set(webhookSignatureHeader, signBody(glRcvr.signingKey, "msg_1", now, tc.reqBody))
+			}
 			require.NoError(t, err, "Unable to create a request")
 
 			resp, err := http.DefaultClient.Do(request)
@@ -154,6 +253,784 @@ func TestGitlabReceiverHttpServer(t *testing.T) {
 	}
 }
 
+func TestGitlabReceiverMaxRequestBodySize(t *testing.T) {
+	body, err := json.Marshal(newFinishedPipelineEvent())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		limit      int64
+		statusCode int
+	}{
+		{
+			name:       "body exceeds the limit",
+			limit:      int64(len(body)) - 1,
+			statusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "body within the limit",
+			limit:      int64(len(body)),
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := getFreePort()
+			require.NoError(t, err, "error finding an available port")
+			cfg := createDefaultConfig().(*Config)
+			cfg.Endpoint = fmt.Sprintf("localhost:%s", p)
+			cfg.MaxRequestBodySize = tc.limit
+			cfg.SigningKey = testSigningKey
+			glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+			sink := new(consumertest.TracesSink)
+			glRcvr.nextTracesConsumer = sink
+
+			//The limit of the server applies before the signature is verified
+			require.NoError(t, glRcvr.Start(context.Background(), componenttest.NewNopHost()))
+			t.Cleanup(func() {
+				require.NoError(t, glRcvr.Shutdown(context.Background()))
+			})
+			request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s%s", cfg.Endpoint, cfg.Traces.UrlPath), bytes.NewReader(body))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+
+			resp, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, tc.statusCode, resp.StatusCode)
+			assert.Equal(t, 0, sink.SpanCount())
+		})
+	}
+}
+
+func TestGitlabReceiverJobEvents(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	p := newFinishedPipelineEvent()
+	jobEvent := func(id int, failureReason string) *glJobEvent {
+		return &glJobEvent{Kind: "build", Id: id, PipelineId: p.Pipeline.Id, Name: "test", Stage: "test", Status: "failed", FailureReason: failureReason, StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime, Project: p.Project}
+	}
+
+	//Job events are stored until the pipeline is finished
+	res := sendEvent(t, glRcvr, "Job Hook", jobEvent(11, "script_failure"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, 0, sink.SpanCount(), "job events must not be exported before the pipeline is finished")
+
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 1)
+	spans := collectSpans(sink.AllTraces()[0])
+	failureReason, _ := spans["Job: test - 11 - Stage: test"].Attributes().Get(conventionsAttributeCiCdJobFailureReason)
+	assert.Equal(t, "script_failure", failureReason.Str())
+
+	//Job events of already exported jobs are ignored
+	res = sendEvent(t, glRcvr, "Job Hook", jobEvent(11, "script_failure"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, sink.AllTraces(), 1)
+
+	//Job events of jobs which aren't part of the exported trace are added to the pipeline trace
+	res = sendEvent(t, glRcvr, "Job Hook", jobEvent(14, "stuck_or_timeout_failure"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 2)
+	traceId, rootSpanId, err := p.traceContext()
+	require.NoError(t, err)
+	job := collectSpans(sink.AllTraces()[1])["Job: test - 14 - Stage: test"]
+	assert.Equal(t, pcommon.TraceID(traceId), job.TraceID())
+	assert.Equal(t, pcommon.SpanID(rootSpanId), job.ParentSpanID())
+}
+
+// A job event which is received while its pipeline is exported must not get lost, run with -race to detect data races
+func TestGitlabReceiverJobEventDuringPipelineExport(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	var err error
+	glRcvr.nextTracesConsumer, err = consumer.NewTraces(func(ctx context.Context, td ptrace.Traces) error {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		return sink.ConsumeTraces(ctx, td)
+	})
+	require.NoError(t, err)
+
+	//The pipeline has read the stored job events, but isn't marked as exported yet
+	p := newFinishedPipelineEvent()
+	pipelineRes := make(chan *httptest.ResponseRecorder)
+	go func() { pipelineRes <- sendEvent(t, glRcvr, "Pipeline Hook", p) }()
+	<-started
+
+	job := &glJobEvent{Kind: "build", Id: 14, PipelineId: p.Pipeline.Id, Name: "test", Stage: "test", Status: "failed", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime, Project: p.Project}
+	res := sendEvent(t, glRcvr, "Job Hook", job)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	close(release)
+	res = <-pipelineRes
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	//The job is exported into the pipeline trace once the pipeline is marked as exported
+	require.Len(t, sink.AllTraces(), 2)
+	assert.NotContains(t, collectSpans(sink.AllTraces()[0]), "Job: test - 14 - Stage: test")
+	span, ok := collectSpans(sink.AllTraces()[1])["Job: test - 14 - Stage: test"]
+	require.True(t, ok)
+	traceId, rootSpanId, err := p.traceContext()
+	require.NoError(t, err)
+	assert.Equal(t, pcommon.TraceID(traceId), span.TraceID())
+	assert.Equal(t, pcommon.SpanID(rootSpanId), span.ParentSpanID())
+
+	//Redelivered job events of the job are ignored
+	res = sendEvent(t, glRcvr, "Job Hook", job)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, sink.AllTraces(), 2)
+}
+
+func TestGitlabReceiverRetriedPipelines(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	rootSpan := func(traces ptrace.Traces) ptrace.Span {
+		return traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	}
+	attempt := func(attrs pcommon.Map) int64 {
+		v, _ := attrs.Get(conventionsAttributeCiCdPipelineAttempt)
+		return v.Int()
+	}
+
+	first := newFinishedPipelineEvent()
+	res := sendEvent(t, glRcvr, "Pipeline Hook", first)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 1)
+	assert.Equal(t, int64(1), attempt(rootSpan(sink.AllTraces()[0]).Attributes()))
+	assert.Equal(t, 0, rootSpan(sink.AllTraces()[0]).Links().Len())
+
+	//The retried pipeline finishes again and is exported as new trace which links to the first attempt
+	retried := newFinishedPipelineEvent()
+	retried.Pipeline.FinishedAt = "2024-01-01 13:40:15 UTC"
+	res = sendEvent(t, glRcvr, "Pipeline Hook", retried)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 2)
+	root := rootSpan(sink.AllTraces()[1])
+	assert.Equal(t, int64(2), attempt(root.Attributes()))
+	require.Equal(t, 1, root.Links().Len())
+	traceId, rootSpanId, err := first.traceContext()
+	require.NoError(t, err)
+	assert.Equal(t, pcommon.TraceID(traceId), root.Links().At(0).TraceID())
+	assert.Equal(t, pcommon.SpanID(rootSpanId), root.Links().At(0).SpanID())
+	assert.Equal(t, int64(1), attempt(root.Links().At(0).Attributes()))
+
+	//A redelivered event of the first attempt keeps its attempt and doesn't link to later attempts
+	res = sendEvent(t, glRcvr, "Pipeline Hook", first)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 3)
+	root = rootSpan(sink.AllTraces()[2])
+	assert.Equal(t, int64(1), attempt(root.Attributes()))
+	assert.Equal(t, 0, root.Links().Len())
+
+	//The attempts keep counting once the oldest attempts are dropped
+	for i := 3; i <= storeMaxAttempts+2; i++ {
+		retried := newFinishedPipelineEvent()
+		retried.Pipeline.FinishedAt = fmt.Sprintf("2024-01-02 %02d:%02d:00 UTC", i/60, i%60)
+		res = sendEvent(t, glRcvr, "Pipeline Hook", retried)
+		assert.Equal(t, http.StatusOK, res.Code)
+	}
+	root = rootSpan(sink.AllTraces()[len(sink.AllTraces())-1])
+	assert.Equal(t, int64(storeMaxAttempts+2), attempt(root.Attributes()))
+	require.Equal(t, storeMaxAttempts, root.Links().Len())
+	assert.Equal(t, int64(2), attempt(root.Links().At(0).Attributes()))
+}
+
+func TestGitlabReceiverRetriedPipelinesStableTraceId(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.StableTraceId = true
+	cfg.Traces.StageSpans = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	res := sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	//The failed job is retried, the successful build job is part of the retried pipeline as well
+	retried := newFinishedPipelineEvent()
+	retried.Pipeline.FinishedAt = "2024-01-01 13:40:15 UTC"
+	retried.Jobs = append(retried.Jobs, Job{Id: 14, Name: "test", Stage: "test", Status: "success", StartedAt: "2024-01-01 13:30:15 UTC", FinishedAt: "2024-01-01 13:40:15 UTC"})
+	res = sendEvent(t, glRcvr, "Pipeline Hook", retried)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 2)
+
+	//The attempts share the trace, the retried pipeline is the second attempt with its own root span
+	first := collectSpans(sink.AllTraces()[0])
+	second := collectSpans(sink.AllTraces()[1])
+	firstRoot := first["Gitlab Pipeline: 1 - https://gitlab.com/group/project/-/pipelines/1"]
+	root := second["Gitlab Pipeline: 1 - https://gitlab.com/group/project/-/pipelines/1"]
+	assert.Equal(t, firstRoot.TraceID(), root.TraceID())
+	assert.NotEqual(t, firstRoot.SpanID(), root.SpanID())
+	v, _ := root.Attributes().Get(conventionsAttributeCiCdPipelineAttempt)
+	assert.Equal(t, int64(2), v.Int())
+	require.Equal(t, 1, root.Links().Len())
+	assert.Equal(t, firstRoot.SpanID(), root.Links().At(0).SpanID())
+
+	//Only the retried job and its stage are added to the trace, no span id is exported twice
+	assert.Len(t, second, 3)
+	assert.Contains(t, second, "Job: test - 14 - Stage: test")
+	spanIds := make(map[pcommon.SpanID]string)
+	for _, spans := range []map[string]ptrace.Span{first, second} {
+		for name, span := range spans {
+			assert.NotContains(t, spanIds, span.SpanID(), "%s and %s", name, spanIds[span.SpanID()])
+			spanIds[span.SpanID()] = name
+		}
+	}
+}
+
+func TestGitlabReceiverDownstreamPipelines(t *testing.T) {
+	newChildPipelineEvent := func(bridgeJobId int) *glPipelineEvent {
+		p := newFinishedPipelineEvent()
+		p.Pipeline.Id = 2
+		p.Pipeline.Source = "parent_pipeline"
+		p.ParentPipeline = ParentPipeline{Id: 1, JobId: bridgeJobId, Project: p.Project}
+		return p
+	}
+	rootSpan := func(traces ptrace.Traces) ptrace.Span {
+		return traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	}
+	linkType := func(link ptrace.SpanLink) string {
+		v, _ := link.Attributes().Get(conventionsAttributeCiCdPipelineLinkType)
+		return v.Str()
+	}
+
+	tests := []struct {
+		name          string
+		childFirst    bool
+		bridgeJobId   int
+		linkedFromJob bool
+	}{
+		{
+			name:          "child pipeline finishes before the parent pipeline",
+			childFirst:    true,
+			bridgeJobId:   12,
+			linkedFromJob: true,
+		},
+		{
+			name:          "child pipeline finishes after the parent pipeline",
+			bridgeJobId:   12,
+			linkedFromJob: true,
+		},
+		{
+			name:        "bridge job isn't part of the parent pipeline trace",
+			bridgeJobId: 99,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+			sink := new(consumertest.TracesSink)
+			glRcvr.nextTracesConsumer = sink
+
+			parent := newFinishedPipelineEvent()
+			child := newChildPipelineEvent(tc.bridgeJobId)
+			events := []*glPipelineEvent{parent, child}
+			if tc.childFirst {
+				events = []*glPipelineEvent{child, parent}
+			}
+			for _, e := range events {
+				res := sendEvent(t, glRcvr, "Pipeline Hook", e)
+				assert.Equal(t, http.StatusOK, res.Code)
+			}
+			require.Len(t, sink.AllTraces(), 2)
+
+			parentTraceId, parentRootSpanId, err := parent.traceContext()
+			require.NoError(t, err)
+			childTraceId, childRootSpanId, err := child.traceContext()
+			require.NoError(t, err)
+			bridgeSpanId, err := parent.jobSpanId(12)
+			require.NoError(t, err)
+
+			if tc.childFirst {
+				//The parent pipeline finishes last and links the child pipeline from its bridge job span
+				assert.Equal(t, 0, rootSpan(sink.AllTraces()[0]).Links().Len())
+				bridge := collectSpans(sink.AllTraces()[1])["Job: build - 12 - Stage: build"]
+				require.Equal(t, 1, bridge.Links().Len())
+				assert.Equal(t, pcommon.TraceID(childTraceId), bridge.Links().At(0).TraceID())
+				assert.Equal(t, pcommon.SpanID(childRootSpanId), bridge.Links().At(0).SpanID())
+				assert.Equal(t, "downstream", linkType(bridge.Links().At(0)))
+				return
+			}
+
+			//The child pipeline finishes last and links its parent pipeline
+			root := rootSpan(sink.AllTraces()[1])
+			require.Equal(t, 1, root.Links().Len())
+			assert.Equal(t, pcommon.TraceID(parentTraceId), root.Links().At(0).TraceID())
+			assert.Equal(t, "upstream", linkType(root.Links().At(0)))
+			if tc.linkedFromJob {
+				assert.Equal(t, pcommon.SpanID(bridgeSpanId), root.Links().At(0).SpanID())
+			} else {
+				assert.Equal(t, pcommon.SpanID(parentRootSpanId), root.Links().At(0).SpanID())
+			}
+		})
+	}
+}
+
+func TestGitlabReceiverStageSpans(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.StageSpans = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	p := newFinishedPipelineEvent()
+	res := sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 1)
+	spans := collectSpans(sink.AllTraces()[0])
+	require.Contains(t, spans, "Stage: test")
+
+	//Job events which are exported after the pipeline trace are added to their stage span
+	res = sendEvent(t, glRcvr, "Job Hook", &glJobEvent{Kind: "build", Id: 14, PipelineId: p.Pipeline.Id, Name: "test", Stage: "test", Status: "failed", StartedAt: gitlabStartTime, FinishedAt: gitlabEndTime, Project: p.Project})
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 2)
+	job := collectSpans(sink.AllTraces()[1])["Job: test - 14 - Stage: test"]
+	assert.Equal(t, spans["Stage: test"].SpanID(), job.ParentSpanID())
+}
+
+func TestGitlabReceiverStableTraceId(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.StableTraceId = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	res := sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 1)
+
+	//Spans created within the job with the traceparent are children of the job span
+	job := collectSpans(sink.AllTraces()[0])["Job: build - 12 - Stage: build"]
+	traceParent, err := TraceParent("abc123", 1, 12)
+	require.NoError(t, err)
+	assert.Equal(t, "00-"+job.TraceID().String()+"-"+job.SpanID().String()+"-01", traceParent)
+}
+
+func TestGitlabReceiverTracesQueue(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = consumertest.NewErr(errors.New("pipeline is backed up"))
+	client := newMemoryStorageClient()
+	glRcvr.tracesQueue = newTracesQueue(newTestQueueConfig(), client, glRcvr.nextTracesConsumer, zap.NewNop())
+
+	//The webhook is acknowledged once the traces are persisted, even if the pipeline is backed up
+	res := sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotNil(t, client.data[queueItemKey(0)])
+
+	glRcvr.tracesQueue.cfg.QueueSize = 1
+	res = sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusInternalServerError, res.Code, "traces which can't be queued aren't acknowledged")
+}
+
+func TestGitlabReceiverDedup(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Dedup.Enabled = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	send := func(uuid string, event any) *httptest.ResponseRecorder {
+		body, err := json.Marshal(event)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, cfg.Traces.UrlPath, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+		if uuid != "" {
+			req.Header.Set(gitlabEventUUIDHeader, uuid)
+		}
+		res := httptest.NewRecorder()
+		glRcvr.handleEvent(context.Background(), res, req, []pipeline.Signal{pipeline.SignalTraces})
+		return res
+	}
+
+	res := send("9cb8e8c4-2a39-4b1f-a0a4-0b1f4c2b1c11", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = send("9cb8e8c4-2a39-4b1f-a0a4-0b1f4c2b1c11", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "Already handled", res.Body.String())
+	assert.Len(t, sink.AllTraces(), 1, "redelivered webhooks are not exported again")
+
+	//Without event uuid finished pipelines are identified by their id, status and finished time
+	res = send("", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = send("", newFinishedPipelineEvent())
+	assert.Equal(t, "Already handled", res.Body.String())
+	assert.Len(t, sink.AllTraces(), 2)
+
+	retried := newFinishedPipelineEvent()
+	retried.Pipeline.FinishedAt = "2024-01-01 13:40:15 UTC"
+	res = send("", retried)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, sink.AllTraces(), 3, "retried pipelines are exported again")
+
+	//Webhooks which failed to export are handled again if they are redelivered
+	glRcvr.nextTracesConsumer = consumertest.NewErr(errors.New("pipeline is backed up"))
+	res = send("0f3c2a51-7d8e-4f4b-9a61-1d2e3f4a5b6c", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	glRcvr.nextTracesConsumer = sink
+	res = send("0f3c2a51-7d8e-4f4b-9a61-1d2e3f4a5b6c", newFinishedPipelineEvent())
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, sink.AllTraces(), 4)
+}
+
+func TestGitlabReceiverDedupConcurrentDeliveries(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Dedup.Enabled = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var err error
+	glRcvr.nextTracesConsumer, err = consumer.NewTraces(func(ctx context.Context, td ptrace.Traces) error {
+		close(started)
+		<-release
+		return sink.ConsumeTraces(ctx, td)
+	})
+	require.NoError(t, err)
+
+	send := func() *httptest.ResponseRecorder {
+		body, err := json.Marshal(newFinishedPipelineEvent())
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, cfg.Traces.UrlPath, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+		req.Header.Set(gitlabEventUUIDHeader, "9cb8e8c4-2a39-4b1f-a0a4-0b1f4c2b1c11")
+		res := httptest.NewRecorder()
+		glRcvr.handleEvent(context.Background(), res, req, []pipeline.Signal{pipeline.SignalTraces})
+		return res
+	}
+
+	//Gitlab retries the delivery while the export of the first delivery is still blocked
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send() }()
+	<-started
+	res := send()
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	close(release)
+	res = <-first
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "Already handled", send().Body.String())
+	assert.Len(t, sink.AllTraces(), 1, "the event is exported once")
+}
+
+func TestGitlabReceiverDedupPartialExport(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Dedup.Enabled = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	metricsSink := new(consumertest.MetricsSink)
+	glRcvr.nextTracesConsumer = consumertest.NewErr(errors.New("pipeline is backed up"))
+	glRcvr.nextMetricsConsumer = metricsSink
+
+	//The metrics of the event are exported, the traces fail
+	res := sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent(), pipeline.SignalTraces, pipeline.SignalMetrics)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+
+	//The redelivery only exports the traces, the metrics aren't counted twice
+	tracesSink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = tracesSink
+	res = sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent(), pipeline.SignalTraces, pipeline.SignalMetrics)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, tracesSink.AllTraces(), 1)
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+}
+
+func TestGitlabReceiverBackfill(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	cfg := createDefaultConfig().(*Config)
+	p, err := getFreePort()
+	require.NoError(t, err)
+	cfg.Endpoint = fmt.Sprintf("localhost:%s", p)
+	cfg.API.Endpoint = gitlab.URL
+	cfg.API.Token = testAPIToken
+	cfg.API.Projects = []string{"group/project"}
+	cfg.Traces.Backfill.Enabled = true
+	cfg.Traces.Backfill.Window = 100 * 365 * 24 * time.Hour
+
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+	require.NoError(t, glRcvr.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() {
+		require.NoError(t, glRcvr.Shutdown(context.Background()))
+	})
+
+	require.Eventually(t, func() bool { return len(sink.AllTraces()) == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	//Job events of backfilled pipelines are added to the existing trace
+	_, ok := glRcvr.pipelines.getExported(1)
+	assert.True(t, ok)
+}
+
+func TestGitlabReceiverDeploymentEvents(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	deploymentEvent := func(id int, deployableId int, status string, changedAt string) *glDeploymentEvent {
+		d := newDeploymentEvent(id, deployableId, status)
+		d.StatusChangedAt = changedAt
+		d.startedAt = ""
+		return d
+	}
+
+	//Deployments are stored until the pipeline is finished, the running deployment defines the start of the span
+	res := sendEvent(t, glRcvr, "Deployment Hook", deploymentEvent(5, 12, "running", "2024-01-01 13:35:15 +0100"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = sendEvent(t, glRcvr, "Deployment Hook", deploymentEvent(5, 12, "success", "2024-01-01 13:40:15 +0100"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, 0, sink.SpanCount(), "deployments must not be exported before the pipeline is finished")
+
+	p := newFinishedPipelineEvent()
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 1)
+	spans := collectSpans(sink.AllTraces()[0])
+	deployment, ok := spans["Deployment: production - 5"]
+	require.True(t, ok, "the deployment is part of the pipeline trace")
+	assert.Equal(t, spans["Job: build - 12 - Stage: build"].SpanID(), deployment.ParentSpanID())
+	assert.Equal(t, getParsedGitlabTime("2024-01-01 12:35:15 UTC"), deployment.StartTimestamp())
+
+	//Deployments of already exported pipelines are added to the pipeline trace
+	res = sendEvent(t, glRcvr, "Deployment Hook", deploymentEvent(6, 11, "failed", "2024-01-01 13:45:15 +0100"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 2)
+	traceId, rootSpanId, err := p.traceContext()
+	require.NoError(t, err)
+	deployment = collectSpans(sink.AllTraces()[1])["Deployment: production - 6"]
+	assert.Equal(t, pcommon.TraceID(traceId), deployment.TraceID())
+	assert.Equal(t, spans["Job: test - 11 - Stage: test"].SpanID(), deployment.ParentSpanID(), "the deployment is a child of its exported job span")
+	assert.Equal(t, ptrace.StatusCodeError, deployment.Status().Code())
+
+	//Deployments which were already exported are ignored
+	res = sendEvent(t, glRcvr, "Deployment Hook", deploymentEvent(6, 11, "failed", "2024-01-01 13:45:15 +0100"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, sink.AllTraces(), 2)
+
+	//Deployments of jobs which aren't part of the exported trace are children of the root span
+	res = sendEvent(t, glRcvr, "Deployment Hook", deploymentEvent(7, 13, "success", "2024-01-01 13:50:15 +0100"))
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, sink.AllTraces(), 3)
+	deployment = collectSpans(sink.AllTraces()[2])["Deployment: production - 7"]
+	assert.Equal(t, pcommon.SpanID(rootSpanId), deployment.ParentSpanID())
+}
+
+func TestGitlabReceiverMetrics(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	tracesSink := new(consumertest.TracesSink)
+	metricsSink := new(consumertest.MetricsSink)
+	glRcvr.nextTracesConsumer = tracesSink
+	glRcvr.nextMetricsConsumer = metricsSink
+
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Ref = "feature"
+	glRcvr.cfg.Traces.Refs = []string{"main"}
+	var err error
+	glRcvr.refs, err = newRefFilter(glRcvr.cfg.Traces.Refs, nil)
+	require.NoError(t, err)
+
+	//The refs are only applied to traces
+	res := sendEvent(t, glRcvr, "Pipeline Hook", p, pipeline.SignalTraces, pipeline.SignalMetrics)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "OK", res.Body.String())
+	assert.Equal(t, 0, tracesSink.SpanCount())
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+
+	//Job events don't create metrics
+	res = sendEvent(t, glRcvr, "Job Hook", &glJobEvent{Kind: "build", Id: 1}, pipeline.SignalMetrics)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+
+	//Metrics have their own refs
+	glRcvr.metricsRefs, err = newRefFilter(nil, []string{"feature"})
+	require.NoError(t, err)
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p, pipeline.SignalMetrics)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+}
+
+func TestGitlabReceiverDoraMetrics(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Metrics.Dora.Enabled = true
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	metricsSink := new(consumertest.MetricsSink)
+	glRcvr.nextMetricsConsumer = metricsSink
+
+	res := sendEvent(t, glRcvr, "Pipeline Hook", newDeploymentPipelineEvent(21, "success", gitlabEndTime), pipeline.SignalMetrics)
+	assert.Equal(t, http.StatusOK, res.Code)
+	require.Len(t, metricsSink.AllMetrics(), 1)
+	got := collectMetrics(metricsSink.AllMetrics()[0])
+	assert.Contains(t, got, metricPipelineRuns)
+	assert.Contains(t, got, metricDoraDeployments)
+	assert.Contains(t, got, metricDoraLeadTime)
+}
+
+func TestGitlabReceiverLogs(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	tracesSink := new(consumertest.TracesSink)
+	logsSink := new(consumertest.LogsSink)
+	glRcvr.nextTracesConsumer = tracesSink
+	glRcvr.nextLogsConsumer = logsSink
+
+	//Every state transition is exported as log record, only finished pipelines as traces
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Status = "running"
+	p.Pipeline.FinishedAt = ""
+	res := sendEvent(t, glRcvr, "Pipeline Hook", p, pipeline.SignalTraces, pipeline.SignalLogs)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, 0, tracesSink.SpanCount())
+	assert.Equal(t, 1, logsSink.LogRecordCount())
+
+	res = sendEvent(t, glRcvr, "Pipeline Hook", newFinishedPipelineEvent(), pipeline.SignalTraces, pipeline.SignalLogs)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, 1, len(tracesSink.AllTraces()))
+	assert.Equal(t, 2, logsSink.LogRecordCount())
+
+	res = sendEvent(t, glRcvr, "Job Hook", &glJobEvent{Kind: "build", Id: 11, PipelineId: 1, Status: "running", StartedAt: gitlabStartTime}, pipeline.SignalLogs)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, 3, logsSink.LogRecordCount())
+
+	//Deployment and merge request hooks are exported as log records with only a logs pipeline as well
+	res = sendEvent(t, glRcvr, "Deployment Hook", newDeploymentEvent(5, 13, "running"), pipeline.SignalLogs)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "OK", res.Body.String())
+	assert.Equal(t, 4, logsSink.LogRecordCount())
+
+	res = sendEvent(t, glRcvr, "Merge Request Hook", newMergeRequestEvent("update", "2024-01-01 11:00:00 UTC"), pipeline.SignalLogs)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "OK", res.Body.String())
+	assert.Equal(t, 5, logsSink.LogRecordCount())
+	assert.Len(t, tracesSink.AllTraces(), 1)
+}
+
+// Every exported trace must belong to exactly one of the concurrently sent pipeline events, run with -race to detect data races
+func TestGitlabReceiverConcurrentPipelineEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cfg := createDefaultConfig().(*Config)
+	p, err := getFreePort()
+	require.NoError(t, err, "error finding an available port")
+	cfg.Endpoint = fmt.Sprintf("localhost:%s", p)
+
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+	require.NoError(t, glRcvr.Start(ctx, componenttest.NewNopHost()), "failed to start http server")
+	t.Cleanup(func() {
+		require.NoError(t, glRcvr.Shutdown(ctx), "failed to shutdown http server")
+	})
+
+	const eventCount = 300
+	events := make(map[string]*glPipelineEvent, eventCount)
+	for i := 0; i < eventCount; i++ {
+		e := newFinishedPipelineEvent()
+		e.Pipeline.Id = i + 1
+		e.Pipeline.Sha = fmt.Sprintf("sha-%d", i)
+		e.Project.Path = fmt.Sprintf("group/project-%d", i)
+		for j := range e.Jobs {
+			e.Jobs[j].Id = (i+1)*100 + j
+		}
+		events[strconv.Itoa(e.Pipeline.Id)] = e
+	}
+
+	//Limit the connections to not exceed the listen backlog of the server, the requests are still handled concurrently
+	client := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 64, MaxIdleConnsPerHost: 64}}
+	var wg sync.WaitGroup
+	for _, e := range events {
+		wg.Add(1)
+		go func(e *glPipelineEvent) {
+			defer wg.Done()
+			body, err := json.Marshal(e)
+			assert.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s%s", cfg.Endpoint, cfg.Traces.UrlPath), bytes.NewReader(body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+
+			resp, err := client.Do(req)
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.NoError(t, resp.Body.Close())
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	traces := sink.AllTraces()
+	require.Len(t, traces, eventCount)
+	exported := make(map[string]struct{}, eventCount)
+	for _, td := range traces {
+		rs := td.ResourceSpans().At(0)
+		root := rs.ScopeSpans().At(0).Spans().At(0)
+		pipelineId, ok := root.Attributes().Get(conventionsAttributeCidCPipelineRunId)
+		require.True(t, ok, "root span must have a pipeline id")
+		e, ok := events[pipelineId.Str()]
+		require.True(t, ok, "unknown pipeline id %s", pipelineId.Str())
+		exported[pipelineId.Str()] = struct{}{}
+
+		traceId, _, err := e.traceContext()
+		require.NoError(t, err)
+		serviceName, _ := rs.Resource().Attributes().Get("service.name")
+		assert.Equal(t, e.Project.Path, serviceName.Str(), "resource must match the pipeline event")
+
+		spans := collectSpans(td)
+		assert.Len(t, spans, 3, "root span and 2 finished jobs expected")
+		for _, s := range spans {
+			assert.Equal(t, pcommon.TraceID(traceId), s.TraceID(), "all spans must belong to the trace of the pipeline event")
+		}
+		for _, j := range e.Jobs[:2] {
+			assert.Contains(t, spans, fmt.Sprintf("Job: %s - %d - Stage: %s", j.Name, j.Id, j.Stage), "job span must match the pipeline event")
+		}
+	}
+	assert.Len(t, exported, eventCount, "every pipeline must be exported exactly once")
+}
+
+func newTestReceiver(t *testing.T, cfg *Config, settings receiver.Settings) *gitlabReceiver {
+	glRcvr, err := newGitlabReceiver(cfg, settings)
+	require.NoError(t, err)
+	return glRcvr
+}
+
+// Sends the event directly to the handler of the receiver, by default the event is handled as traces
+func sendEvent(t *testing.T, glRcvr *gitlabReceiver, eventType string, event any, signals ...pipeline.Signal) *httptest.ResponseRecorder {
+	if len(signals) == 0 {
+		signals = []pipeline.Signal{pipeline.SignalTraces}
+	}
+
+	body, err := json.Marshal(event)
+	require.NoError(t, err, "Unable to marshal the event")
+
+	req := httptest.NewRequest(http.MethodPost, glRcvr.cfg.Traces.UrlPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gitlab-Event", eventType)
+	res := httptest.NewRecorder()
+	glRcvr.handleEvent(context.Background(), res, req, signals)
+	return res
+}
+
 func getFreePort() (string, error) {
 	listener, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
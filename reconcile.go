@@ -0,0 +1,159 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultReconcileInterval = 15 * time.Minute
+	defaultReconcileWindow   = time.Hour
+	defaultReconcileDelay    = 5 * time.Minute
+)
+
+// reconciler periodically exports the finished pipelines whose webhook wasn't received, e.g. because the delivery failed.
+// A pipeline is missing if the receiver didn't export the trace of the pipeline. Pipelines which finished
+// before the start of the receiver are left to the backfill, because the exported pipelines are only known in memory.
+type reconciler struct {
+	cfg       Reconcile
+	projects  []string
+	logger    *zap.Logger
+	api       *gitlabClient
+	isMissing func(p *glPipelineEvent) bool
+	export    func(ctx context.Context, p *glPipelineEvent) error
+	now       func() time.Time
+	startTime time.Time
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func newReconciler(cfg Reconcile, projects []string, api *gitlabClient, isMissing func(p *glPipelineEvent) bool, export func(ctx context.Context, p *glPipelineEvent) error, logger *zap.Logger) *reconciler {
+	return &reconciler{
+		cfg:       cfg,
+		projects:  projects,
+		logger:    logger,
+		api:       api,
+		isMissing: isMissing,
+		export:    export,
+		now:       time.Now,
+		startTime: time.Now(),
+	}
+}
+
+func (r *reconciler) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.reconcile(ctx)
+			}
+		}
+	}()
+}
+
+func (r *reconciler) shutdown() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// A failing project doesn't stop the reconciliation of the other projects
+func (r *reconciler) reconcile(ctx context.Context) {
+	for _, project := range r.projects {
+		reconciled, err := r.reconcileProject(ctx, project)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			r.logger.Error("Unable to reconcile the pipelines of the project", zap.String("project", project), zap.Int("pipelines", reconciled), zap.Error(err))
+			continue
+		}
+		if reconciled > 0 {
+			r.logger.Info("Exported pipelines with missing webhooks", zap.String("project", project), zap.Int("pipelines", reconciled))
+		}
+	}
+}
+
+// Pipelines which were updated within the delay are skipped, their webhook might still be on its way. They are
+// reconciled with the next run, as long as the window is larger than the interval and the delay.
+func (r *reconciler) reconcileProject(ctx context.Context, project string) (int, error) {
+	now := r.now()
+	since := now.Add(-r.cfg.Window)
+	if r.startTime.After(since) {
+		since = r.startTime
+	}
+	until := now.Add(-r.cfg.Delay)
+
+	p, err := r.api.project(ctx, project)
+	if err != nil {
+		return 0, err
+	}
+	pipelines, err := r.api.pipelines(ctx, project, since)
+	if err != nil {
+		return 0, err
+	}
+
+	reconciled := 0
+	for _, pipeline := range pipelines {
+		updatedAt, err := time.Parse(time.RFC3339, pipeline.UpdatedAt)
+		if err != nil {
+			return reconciled, fmt.Errorf("invalid update time of pipeline %d: %w", pipeline.Id, err)
+		}
+		if updatedAt.After(until) {
+			continue
+		}
+
+		ok, err := r.reconcilePipeline(ctx, project, p, pipeline.Id)
+		if err != nil {
+			return reconciled, fmt.Errorf("unable to reconcile pipeline %d: %w", pipeline.Id, err)
+		}
+		if ok {
+			reconciled++
+		}
+	}
+	return reconciled, nil
+}
+
+// The trace id only depends on the pipeline itself, the jobs are only fetched if the pipeline is missing
+func (r *reconciler) reconcilePipeline(ctx context.Context, project string, p apiProject, pipelineId int) (bool, error) {
+	pipeline, err := r.api.pipeline(ctx, project, pipelineId)
+	if err != nil {
+		return false, err
+	}
+	e, err := newPipelineEventFromAPI(p, pipeline, nil)
+	if err != nil {
+		return false, err
+	}
+	if !r.isMissing(e) {
+		return false, nil
+	}
+
+	jobs, err := r.api.jobs(ctx, project, pipelineId)
+	if err != nil {
+		return false, err
+	}
+	e, err = newPipelineEventFromAPI(p, pipeline, jobs)
+	if err != nil {
+		return false, err
+	}
+	r.logger.Debug("Webhook of the pipeline is missing", zap.String("project", project), zap.Int("pipeline", pipelineId))
+	err = r.export(ctx, e)
+	if errors.Is(err, errNotExported) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,123 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.uber.org/zap"
+)
+
+func TestReconciler(t *testing.T) {
+	tests := []struct {
+		name               string
+		refs               []string
+		delay              time.Duration
+		startTime          time.Time
+		expectedReconciled int
+	}{
+		{
+			name:               "missing pipelines",
+			delay:              defaultReconcileDelay,
+			expectedReconciled: 2,
+		},
+		{
+			name:               "pipelines within the delay",
+			delay:              10 * time.Minute,
+			expectedReconciled: 1,
+		},
+		{
+			name:               "pipelines before the start",
+			delay:              defaultReconcileDelay,
+			startTime:          time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+			expectedReconciled: 1,
+		},
+		{
+			name:               "filtered refs",
+			refs:               []string{"main"},
+			delay:              defaultReconcileDelay,
+			expectedReconciled: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gitlab := newFakeGitlab(t)
+			cfg := createDefaultConfig().(*Config)
+			cfg.Traces.Refs = tc.refs
+			sink := new(consumertest.TracesSink)
+			glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+			glRcvr.nextTracesConsumer = sink
+
+			//The webhook of the first pipeline was received
+			require.NoError(t, glRcvr.handlePipelineTraces(context.Background(), newFinishedPipelineEvent()))
+			sink.Reset()
+
+			r := newTestReconciler(t, gitlab, glRcvr)
+			r.cfg.Delay = tc.delay
+			if !tc.startTime.IsZero() {
+				r.startTime = tc.startTime
+			}
+			reconciled, err := r.reconcileProject(context.Background(), "group/project")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedReconciled, reconciled)
+			assert.Len(t, sink.AllTraces(), tc.expectedReconciled)
+
+			//Reconciled pipelines are only exported once
+			reconciled, err = r.reconcileProject(context.Background(), "group/project")
+			require.NoError(t, err)
+			assert.Equal(t, 0, reconciled)
+		})
+	}
+}
+
+func TestGitlabReceiverIsMissing(t *testing.T) {
+	for _, stableTraceId := range []bool{false, true} {
+		cfg := createDefaultConfig().(*Config)
+		cfg.Traces.StableTraceId = stableTraceId
+		glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+		glRcvr.nextTracesConsumer = new(consumertest.TracesSink)
+
+		assert.True(t, glRcvr.isMissing(newFinishedPipelineEvent()))
+		require.NoError(t, glRcvr.handlePipelineTraces(context.Background(), newFinishedPipelineEvent()))
+		assert.False(t, glRcvr.isMissing(newFinishedPipelineEvent()))
+
+		//A retried pipeline is missing until its webhook was received
+		retried := newFinishedPipelineEvent()
+		retried.Pipeline.FinishedAt = "2024-01-01 12:50:15 UTC"
+		assert.True(t, glRcvr.isMissing(retried), "stable trace id: %v", stableTraceId)
+
+		running := newFinishedPipelineEvent()
+		running.Pipeline.Id = 2
+		running.Pipeline.FinishedAt = ""
+		running.Pipeline.Status = "running"
+		assert.False(t, glRcvr.isMissing(running))
+	}
+}
+
+func TestGitlabReceiverIsMissingProjects(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Projects.Exclude = []string{"group"}
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	sink := new(consumertest.TracesSink)
+	glRcvr.nextTracesConsumer = sink
+
+	//Pipelines of excluded projects are never missing and never exported
+	assert.False(t, glRcvr.isMissing(newFinishedPipelineEvent()))
+	assert.ErrorIs(t, glRcvr.handlePipelineTraces(context.Background(), newFinishedPipelineEvent()), errNotExported)
+	assert.Empty(t, sink.AllTraces())
+}
+
+func newTestReconciler(t *testing.T, gitlab *fakeGitlab, glRcvr *gitlabReceiver) *reconciler {
+	cfg := Reconcile{Enabled: true, Interval: defaultReconcileInterval, Window: defaultReconcileWindow, Delay: defaultReconcileDelay}
+	r := newReconciler(cfg, []string{"group/project"}, newTestGitlabClient(t, gitlab), glRcvr.isMissing, glRcvr.handlePipelineTraces, zap.NewNop())
+	r.now = func() time.Time {
+		return time.Date(2024, 1, 1, 13, 15, 0, 0, time.UTC)
+	}
+	r.startTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return r
+}
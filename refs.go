@@ -0,0 +1,109 @@
+package gitlabreceiver
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+)
+
+// A ref pattern is the name of a ref, a glob (e.g. release/*) or a regular expression enclosed in slashes like in the
+// Gitlab CI rules (e.g. /^release\/.*$/). Names and globs which start with refs/heads/ or refs/tags/ only match
+// branches or tags, regular expressions are matched against the name and the qualified name of the ref.
+type refPattern struct {
+	pattern   string
+	qualified bool
+	regexp    *regexp.Regexp
+}
+
+func newRefPattern(pattern string) (refPattern, error) {
+	re, err := parsePattern(pattern)
+	if err != nil {
+		return refPattern{}, fmt.Errorf("ref: %w", err)
+	}
+	return refPattern{pattern: pattern, qualified: re == nil && strings.HasPrefix(pattern, "refs/"), regexp: re}, nil
+}
+
+func (rp refPattern) matches(r ref) bool {
+	if rp.regexp != nil {
+		return rp.regexp.MatchString(r.name) || rp.regexp.MatchString(r.qualifiedName())
+	}
+	name := r.name
+	if rp.qualified {
+		name = r.qualifiedName()
+	}
+	ok, _ := path.Match(rp.pattern, name)
+	return ok
+}
+
+// A ref of a pipeline, either a branch, a tag or a ref like refs/merge-requests/<iid>/head
+type ref struct {
+	name string
+	tag  bool
+}
+
+func (r ref) qualifiedName() string {
+	switch {
+	case strings.HasPrefix(r.name, "refs/"):
+		return r.name
+	case r.tag:
+		return tagRefPrefix + r.name
+	default:
+		return branchRefPrefix + r.name
+	}
+}
+
+// refFilter exports the pipelines of refs which match one of the included patterns and none of the excluded patterns.
+// Without included patterns all refs are included.
+type refFilter struct {
+	include []refPattern
+	exclude []refPattern
+}
+
+func newRefFilter(include []string, exclude []string) (*refFilter, error) {
+	f := &refFilter{}
+	for _, pattern := range include {
+		rp, err := newRefPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, rp)
+	}
+	for _, pattern := range exclude {
+		rp, err := newRefPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, rp)
+	}
+	return f, nil
+}
+
+// The first ref is the ref of the pipeline itself, the others are further refs the pipeline runs for (e.g. the target
+// branch of a merge request). A pipeline is excluded by its own ref only and included by any of its refs, so excluding
+// main doesn't drop the merge request pipelines which target main.
+func (f *refFilter) matches(refs []ref) bool {
+	if len(refs) > 0 {
+		for _, rp := range f.exclude {
+			if rp.matches(refs[0]) {
+				return false
+			}
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, r := range refs {
+		for _, rp := range f.include {
+			if rp.matches(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
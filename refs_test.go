@@ -0,0 +1,102 @@
+package gitlabreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestRefPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		ref      ref
+		expected bool
+	}{
+		{name: "name", pattern: "main", ref: ref{name: "main"}, expected: true},
+		{name: "other name", pattern: "main", ref: ref{name: "master"}},
+		{name: "tag name", pattern: "v1.0.0", ref: ref{name: "v1.0.0", tag: true}, expected: true},
+		{name: "glob", pattern: "release/*", ref: ref{name: "release/1.0"}, expected: true},
+		{name: "glob doesn't match nested refs", pattern: "release/*", ref: ref{name: "release/1.0/hotfix"}},
+		{name: "branch", pattern: "refs/heads/v*", ref: ref{name: "v1"}, expected: true},
+		{name: "branch pattern and tag", pattern: "refs/heads/v*", ref: ref{name: "v1", tag: true}},
+		{name: "tag", pattern: "refs/tags/v*", ref: ref{name: "v1", tag: true}, expected: true},
+		{name: "tag pattern and branch", pattern: "refs/tags/v*", ref: ref{name: "v1"}},
+		{name: "merge request ref", pattern: "refs/merge-requests/*/head", ref: ref{name: "refs/merge-requests/7/head"}, expected: true},
+		{name: "regexp", pattern: `/^release\/.*$/`, ref: ref{name: "release/1.0/hotfix"}, expected: true},
+		{name: "unanchored regexp", pattern: "/hotfix/", ref: ref{name: "release/1.0/hotfix"}, expected: true},
+		{name: "regexp of tags", pattern: `/^refs\/tags\/v\d+/`, ref: ref{name: "v1", tag: true}, expected: true},
+		{name: "regexp of tags and branch", pattern: `/^refs\/tags\/v\d+/`, ref: ref{name: "v1"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rp, err := newRefPattern(tc.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rp.matches(tc.ref))
+		})
+	}
+}
+
+func TestInvalidRefPattern(t *testing.T) {
+	for _, pattern := range []string{"", "release/[", "/(/"} {
+		_, err := newRefPattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestRefFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		refs     []ref
+		expected bool
+	}{
+		{name: "no patterns", refs: []ref{{name: "feature"}}, expected: true},
+		{name: "included", include: []string{"main", "release/*"}, refs: []ref{{name: "release/1.0"}}, expected: true},
+		{name: "not included", include: []string{"main", "release/*"}, refs: []ref{{name: "feature"}}},
+		{name: "excluded", exclude: []string{"renovate/*"}, refs: []ref{{name: "renovate/go"}}},
+		{name: "not excluded", exclude: []string{"renovate/*"}, refs: []ref{{name: "feature"}}, expected: true},
+		{name: "included and excluded", include: []string{"release/*"}, exclude: []string{"release/old"}, refs: []ref{{name: "release/old"}}},
+		{name: "any ref included", include: []string{"main"}, refs: []ref{{name: "refs/merge-requests/7/head"}, {name: "main"}}, expected: true},
+		{name: "own ref excluded", exclude: []string{"refs/merge-requests/*/head"}, refs: []ref{{name: "refs/merge-requests/7/head"}, {name: "main"}}},
+		{name: "other ref not excluded", exclude: []string{"main"}, refs: []ref{{name: "refs/merge-requests/7/head"}, {name: "main"}}, expected: true},
+		{name: "other ref included and own ref excluded", include: []string{"main"}, exclude: []string{"/merge-requests/"}, refs: []ref{{name: "refs/merge-requests/7/head"}, {name: "main"}}},
+		{name: "only tags", include: []string{"refs/tags/*"}, refs: []ref{{name: "v1", tag: true}}, expected: true},
+		{name: "only tags and branch", include: []string{"refs/tags/*"}, refs: []ref{{name: "main"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := newRefFilter(tc.include, tc.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f.matches(tc.refs))
+		})
+	}
+}
+
+func TestGitlabReceiverTagRefs(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.Refs = []string{"refs/tags/v*"}
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, cfg, receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Ref = "v1.0.0"
+	p.Pipeline.Tag = true
+	res := sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, "OK", res.Body.String())
+
+	//A branch with the name of a tag isn't exported
+	p = newFinishedPipelineEvent()
+	p.Pipeline.Id = 2
+	p.Pipeline.Ref = "v1.0.0"
+	res = sendEvent(t, glRcvr, "Pipeline Hook", p)
+	assert.Equal(t, "Not configured to be exported", res.Body.String())
+	assert.Len(t, sink.AllTraces(), 1)
+}
@@ -0,0 +1,69 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+// The receivers of all signals share the same http server, therefore only one receiver per config is created.
+// The collector starts and shuts down the receiver once per pipeline, but the receiver is only started and shut down once.
+type sharedReceivers struct {
+	mu        sync.Mutex
+	receivers map[*Config]*sharedReceiver
+}
+
+type sharedReceiver struct {
+	*gitlabReceiver
+	startOnce    sync.Once
+	shutdownOnce sync.Once
+	startErr     error
+	shutdownErr  error
+	remove       func()
+}
+
+func newSharedReceivers() *sharedReceivers {
+	return &sharedReceivers{receivers: make(map[*Config]*sharedReceiver)}
+}
+
+func (s *sharedReceivers) getOrCreate(cfg component.Config, settings receiver.Settings) (*sharedReceiver, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := cfg.(*Config)
+	if r, ok := s.receivers[c]; ok {
+		return r, nil
+	}
+
+	glRcvr, err := newGitlabReceiver(cfg, settings)
+	if err != nil {
+		return nil, err
+	}
+	r := &sharedReceiver{
+		gitlabReceiver: glRcvr,
+		remove: func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.receivers, c)
+		},
+	}
+	s.receivers[c] = r
+	return r, nil
+}
+
+func (r *sharedReceiver) Start(ctx context.Context, host component.Host) error {
+	r.startOnce.Do(func() {
+		r.startErr = r.gitlabReceiver.Start(ctx, host)
+	})
+	return r.startErr
+}
+
+func (r *sharedReceiver) Shutdown(ctx context.Context) error {
+	r.shutdownOnce.Do(func() {
+		r.shutdownErr = r.gitlabReceiver.Shutdown(ctx)
+		r.remove()
+	})
+	return r.shutdownErr
+}
@@ -0,0 +1,79 @@
+package gitlabreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signingKeyPrefix        = "whsec_"
+	signatureVersionPrefix  = "v1,"
+	webhookIdHeader         = "webhook-id"
+	webhookTimestampHeader  = "webhook-timestamp"
+	webhookSignatureHeader  = "webhook-signature"
+	defaultSignatureTimeout = 5 * time.Minute
+)
+
+// Gitlab signs webhooks according to the Standard Webhooks specification: https://github.com/standard-webhooks/standard-webhooks/blob/main/spec/standard-webhooks.md
+// The key is base64 encoded and might be prefixed with "whsec_".
+func decodeSigningKey(key string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(key, signingKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+	if len(decoded) == 0 {
+		return nil, errors.New("invalid signing key: key is empty")
+	}
+	return decoded, nil
+}
+
+// The signature is a HMAC-SHA256 over "<webhook-id>.<webhook-timestamp>.<body>". The signature header can contain
+// several space delimited signatures (e.g. during a key rotation), the request is valid if one of them matches.
+func verifySignature(key []byte, tolerance time.Duration, header http.Header, body []byte, now time.Time) error {
+	id := header.Get(webhookIdHeader)
+	timestamp := header.Get(webhookTimestampHeader)
+	signatures := header.Get(webhookSignatureHeader)
+	if id == "" || timestamp == "" || signatures == "" {
+		return errors.New("missing webhook signature headers")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid webhook timestamp: %w", err)
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("webhook timestamp is outside of the tolerance of %s", tolerance)
+	}
+
+	expected := computeSignature(key, id, timestamp, body)
+	for _, s := range strings.Fields(signatures) {
+		sig, found := strings.CutPrefix(s, signatureVersionPrefix)
+		if !found {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(sig)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return errors.New("no matching webhook signature")
+}
+
+func computeSignature(key []byte, id string, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,127 @@
+package gitlabreceiver
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningKey = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+
+func TestDecodeSigningKey(t *testing.T) {
+	key, err := decodeSigningKey(testSigningKey)
+	require.NoError(t, err)
+	assert.NotEmpty(t, key)
+
+	_, err = decodeSigningKey("whsec_not-base64!")
+	assert.Error(t, err)
+
+	_, err = decodeSigningKey("")
+	assert.Error(t, err)
+}
+
+func TestVerifySignature(t *testing.T) {
+	key, err := decodeSigningKey(testSigningKey)
+	require.NoError(t, err)
+
+	now := time.Unix(1704112215, 0)
+	body := []byte(gitlabPipelineEvent)
+	validSignature := signBody(key, "msg_1", now, body)
+
+	tests := []struct {
+		name        string
+		id          string
+		timestamp   time.Time
+		signature   string
+		body        []byte
+		expectedErr bool
+	}{
+		{
+			name:      "valid signature",
+			id:        "msg_1",
+			timestamp: now,
+			signature: validSignature,
+			body:      body,
+		},
+		{
+			name:      "valid signature within multiple signatures",
+			id:        "msg_1",
+			timestamp: now,
+			signature: "v1,aW52YWxpZA== " + validSignature,
+			body:      body,
+		},
+		{
+			name:        "modified body",
+			id:          "msg_1",
+			timestamp:   now,
+			signature:   validSignature,
+			body:        []byte("{}"),
+			expectedErr: true,
+		},
+		{
+			name:        "modified webhook id",
+			id:          "msg_2",
+			timestamp:   now,
+			signature:   validSignature,
+			body:        body,
+			expectedErr: true,
+		},
+		{
+			name:        "stale timestamp",
+			id:          "msg_1",
+			timestamp:   now.Add(-10 * time.Minute),
+			signature:   signBody(key, "msg_1", now.Add(-10*time.Minute), body),
+			body:        body,
+			expectedErr: true,
+		},
+		{
+			name:        "timestamp in the future",
+			id:          "msg_1",
+			timestamp:   now.Add(10 * time.Minute),
+			signature:   signBody(key, "msg_1", now.Add(10*time.Minute), body),
+			body:        body,
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported signature version",
+			id:          "msg_1",
+			timestamp:   now,
+			signature:   "v1a," + validSignature[len(signatureVersionPrefix):],
+			body:        body,
+			expectedErr: true,
+		},
+		{
+			name:        "missing signature",
+			id:          "msg_1",
+			timestamp:   now,
+			body:        body,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(webhookIdHeader, tc.id)
+			header.Set(webhookTimestampHeader, strconv.FormatInt(tc.timestamp.Unix(), 10))
+			header.Set(webhookSignatureHeader, tc.signature)
+
+			err := verifySignature(key, defaultSignatureTimeout, header, tc.body, now)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func signBody(key []byte, id string, timestamp time.Time, body []byte) string {
+	sig := computeSignature(key, id, strconv.FormatInt(timestamp.Unix(), 10), body)
+	return signatureVersionPrefix + base64.StdEncoding.EncodeToString(sig)
+}
@@ -0,0 +1,22 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+)
+
+// The storage name separates the clients of the receiver within the same storage extension
+func getStorageClient(ctx context.Context, host component.Host, storageID component.ID, componentID component.ID, storageName string) (storage.Client, error) {
+	ext, ok := host.GetExtensions()[storageID]
+	if !ok {
+		return nil, fmt.Errorf("storage extension %s not found", storageID)
+	}
+	storageExt, ok := ext.(storage.Extension)
+	if !ok {
+		return nil, fmt.Errorf("extension %s is not a storage extension", storageID)
+	}
+	return storageExt.GetClient(ctx, component.KindReceiver, componentID, storageName)
+}
@@ -0,0 +1,15 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestGetStorageClient(t *testing.T) {
+	_, err := getStorageClient(context.Background(), componenttest.NewNopHost(), component.MustNewID("file_storage"), component.MustNewID("gitlab"), doraStorageName)
+	assert.ErrorContains(t, err, "storage extension file_storage not found")
+}
@@ -0,0 +1,294 @@
+package gitlabreceiver
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	storeMaxPipelines = 10000
+	storeMaxAttempts  = 50
+	storeTTL          = 24 * time.Hour
+	// Merge requests are open much longer than pipelines are running
+	storeMaxMergeRequests         = 10000
+	storeMaxMergeRequestEvents    = 50
+	storeMaxMergeRequestPipelines = 50
+	storeMergeRequestTTL          = 30 * 24 * time.Hour
+)
+
+// pipelineStore keeps track of the pipelines seen by the receiver. Events of other hooks (e.g. job events) can only be
+// attached to a pipeline trace once the pipeline is finished, because the trace id is based on the finished time of the pipeline.
+type pipelineStore struct {
+	// Guards adding job events together with marking pipelines as exported: a job event is either added before its
+	// pipeline is marked as exported and handed over to the pipeline, or it sees the exported pipeline
+	jobsMu    sync.Mutex
+	jobEvents *ttlCache[int, map[int]*glJobEvent]
+	exported  *ttlCache[int, exportedPipeline]
+	// Deployment events don't carry the pipeline id, they are stored by the id of their deployable job instead
+	deployments  *ttlCache[int, *glDeploymentEvent]
+	jobPipelines *ttlCache[int, int]
+	attempts     *ttlCache[int, pipelineAttempts]
+	// Downstream pipelines (child and multi-project pipelines) by the id of their upstream pipeline
+	downstream *ttlCache[int, []pipelineLink]
+	// Lifecycle events and exported pipelines of merge requests
+	mergeRequests *ttlCache[mergeRequestKey, mergeRequestState]
+}
+
+// Merge requests are identified by their iid within their (target) project
+type mergeRequestKey struct {
+	projectId int
+	iid       int
+}
+
+type mergeRequestState struct {
+	// Times of the exported lifecycle events, ordered from the first to the latest event
+	eventTimes []pcommon.Timestamp
+	pipelines  []pipelineLink
+}
+
+// Span of another pipeline trace. Downstream pipelines are exported as separate traces which are connected to
+// their upstream pipeline by span links.
+type pipelineLink struct {
+	pipelineId int
+	// Bridge job of the upstream pipeline which triggered the downstream pipeline
+	bridgeJobId int
+	traceId     [16]byte
+	spanId      [8]byte
+}
+
+// Trace context of an exported attempt of a pipeline, every retry of a finished pipeline is exported as new trace. The
+// attempts are identified by the finished time of the pipeline, with stable trace ids all attempts share the trace id.
+type pipelineAttempt struct {
+	attempt    int
+	finishedAt string
+	traceId    [16]byte
+	rootSpanId [8]byte
+}
+
+// The count of all exported attempts is kept separately, because only the latest attempts are kept
+type pipelineAttempts struct {
+	count  int
+	latest []pipelineAttempt
+}
+
+// Trace context of the last exported trace of a pipeline
+type exportedPipeline struct {
+	sha           string
+	pipelineId    int
+	hashTime      string
+	finishedAt    string
+	traceId       [16]byte
+	rootSpanId    [8]byte
+	stageSpanIds  map[string][8]byte
+	jobIds        map[int]struct{}
+	deploymentIds map[int]struct{}
+}
+
+func (p exportedPipeline) jobSpanId(jobId int) ([8]byte, error) {
+	return getJobSpanId(p.sha, strconv.Itoa(p.pipelineId), p.hashTime, strconv.Itoa(jobId))
+}
+
+func (p exportedPipeline) deploymentSpanId(deploymentId int) ([8]byte, error) {
+	return getDeploymentSpanId(p.sha, strconv.Itoa(p.pipelineId), p.hashTime, strconv.Itoa(deploymentId))
+}
+
+func newPipelineStore() *pipelineStore {
+	return &pipelineStore{
+		jobEvents:     newTTLCache[int, map[int]*glJobEvent](storeMaxPipelines, storeTTL),
+		exported:      newTTLCache[int, exportedPipeline](storeMaxPipelines, storeTTL),
+		deployments:   newTTLCache[int, *glDeploymentEvent](storeMaxPipelines, storeTTL),
+		jobPipelines:  newTTLCache[int, int](storeMaxPipelines, storeTTL),
+		attempts:      newTTLCache[int, pipelineAttempts](storeMaxPipelines, storeTTL),
+		downstream:    newTTLCache[int, []pipelineLink](storeMaxPipelines, storeTTL),
+		mergeRequests: newTTLCache[mergeRequestKey, mergeRequestState](storeMaxMergeRequests, storeMergeRequestTTL),
+	}
+}
+
+// Only the latest event of a job is kept. If the pipeline of the job was already exported, its trace is returned.
+func (s *pipelineStore) addJobEvent(e *glJobEvent) (exportedPipeline, bool) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	s.jobEvents.update(e.PipelineId, func(events map[int]*glJobEvent, _ bool) (map[int]*glJobEvent, bool) {
+		events = maps.Clone(events)
+		if events == nil {
+			events = make(map[int]*glJobEvent)
+		}
+		events[e.Id] = e
+		return events, true
+	})
+	return s.exported.get(e.PipelineId)
+}
+
+func (s *pipelineStore) getJobEvents(pipelineId int) map[int]*glJobEvent {
+	events, _ := s.jobEvents.get(pipelineId)
+	return events
+}
+
+// The jobs of the exported pipeline are remembered, so that deployments of these jobs can be added to the pipeline trace.
+// Finished job events which were added while the pipeline was exported aren't part of its trace, they are returned so
+// that they can be added to the pipeline trace on their own.
+func (s *pipelineStore) setExported(pipelineId int, p exportedPipeline, jobIds []int) []*glJobEvent {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	s.exported.set(pipelineId, p)
+	for _, id := range jobIds {
+		s.jobPipelines.set(id, pipelineId)
+	}
+
+	events, _ := s.jobEvents.get(pipelineId)
+	missed := make([]*glJobEvent, 0)
+	for id, e := range events {
+		if _, ok := p.jobIds[id]; !ok && e.FinishedAt != "" {
+			missed = append(missed, e)
+		}
+	}
+	return missed
+}
+
+func (s *pipelineStore) getExported(pipelineId int) (exportedPipeline, bool) {
+	return s.exported.get(pipelineId)
+}
+
+func (s *pipelineStore) addExportedJob(pipelineId int, jobId int) {
+	s.jobPipelines.set(jobId, pipelineId)
+	s.exported.update(pipelineId, func(p exportedPipeline, found bool) (exportedPipeline, bool) {
+		if !found {
+			return p, false
+		}
+		p.jobIds = maps.Clone(p.jobIds)
+		if p.jobIds == nil {
+			p.jobIds = make(map[int]struct{})
+		}
+		p.jobIds[jobId] = struct{}{}
+		return p, true
+	})
+}
+
+func (s *pipelineStore) addExportedDeployment(pipelineId int, deploymentId int) {
+	s.exported.update(pipelineId, func(p exportedPipeline, found bool) (exportedPipeline, bool) {
+		if !found {
+			return p, false
+		}
+		p.deploymentIds = maps.Clone(p.deploymentIds)
+		if p.deploymentIds == nil {
+			p.deploymentIds = make(map[int]struct{})
+		}
+		p.deploymentIds[deploymentId] = struct{}{}
+		return p, true
+	})
+}
+
+// Only the latest event of a deployment is kept, the start time is taken over from the running event of the same deployment.
+// The event must not be modified after it was added.
+func (s *pipelineStore) addDeployment(d *glDeploymentEvent) {
+	s.deployments.update(d.DeployableId, func(prev *glDeploymentEvent, found bool) (*glDeploymentEvent, bool) {
+		if found && prev.Id == d.Id {
+			d.startedAt = prev.startedAt
+		}
+		if d.startedAt == "" && d.Status == "running" {
+			d.startedAt = d.StatusChangedAt
+		}
+		return d, true
+	})
+}
+
+// Deployments of the given jobs by job id
+func (s *pipelineStore) getDeployments(jobIds []int) map[int]*glDeploymentEvent {
+	deployments := make(map[int]*glDeploymentEvent)
+	for _, id := range jobIds {
+		if d, ok := s.deployments.get(id); ok {
+			deployments[id] = d
+		}
+	}
+	return deployments
+}
+
+func (s *pipelineStore) getJobPipeline(jobId int) (int, bool) {
+	return s.jobPipelines.get(jobId)
+}
+
+// Redelivered pipeline events don't add a new attempt. Only the latest attempts are kept.
+func (s *pipelineStore) addAttempt(pipelineId int, a pipelineAttempt) {
+	s.attempts.update(pipelineId, func(attempts pipelineAttempts, _ bool) (pipelineAttempts, bool) {
+		if _, ok := attempts.get(a.finishedAt); ok {
+			return attempts, false
+		}
+		attempts.count = max(attempts.count, a.attempt)
+		attempts.latest = append(slices.Clone(attempts.latest), a)
+		if len(attempts.latest) > storeMaxAttempts {
+			attempts.latest = attempts.latest[len(attempts.latest)-storeMaxAttempts:]
+		}
+		return attempts, true
+	})
+}
+
+// Attempts of the pipeline, the latest attempts are ordered from the first to the latest attempt
+func (s *pipelineStore) getAttempts(pipelineId int) pipelineAttempts {
+	attempts, _ := s.attempts.get(pipelineId)
+	return attempts
+}
+
+func (a pipelineAttempts) get(finishedAt string) (pipelineAttempt, bool) {
+	for _, attempt := range a.latest {
+		if attempt.finishedAt == finishedAt {
+			return attempt, true
+		}
+	}
+	return pipelineAttempt{}, false
+}
+
+// Only the latest exported trace of a downstream pipeline is kept
+func (s *pipelineStore) addDownstream(upstreamId int, l pipelineLink) {
+	s.downstream.update(upstreamId, func(links []pipelineLink, _ bool) ([]pipelineLink, bool) {
+		links = slices.DeleteFunc(slices.Clone(links), func(existing pipelineLink) bool {
+			return existing.pipelineId == l.pipelineId
+		})
+		return append(links, l), true
+	})
+}
+
+func (s *pipelineStore) getDownstream(upstreamId int) []pipelineLink {
+	links, _ := s.downstream.get(upstreamId)
+	return links
+}
+
+func (s *pipelineStore) getMergeRequest(key mergeRequestKey) mergeRequestState {
+	state, _ := s.mergeRequests.get(key)
+	return state
+}
+
+// Redelivered lifecycle events don't add a new event time. Only the latest events are kept.
+func (s *pipelineStore) addMergeRequestEvent(key mergeRequestKey, t pcommon.Timestamp) {
+	s.mergeRequests.update(key, func(state mergeRequestState, _ bool) (mergeRequestState, bool) {
+		i, found := slices.BinarySearch(state.eventTimes, t)
+		if found {
+			return state, false
+		}
+		state.eventTimes = slices.Insert(slices.Clone(state.eventTimes), i, t)
+		if len(state.eventTimes) > storeMaxMergeRequestEvents {
+			state.eventTimes = state.eventTimes[len(state.eventTimes)-storeMaxMergeRequestEvents:]
+		}
+		return state, true
+	})
+}
+
+// Only the latest exported trace of a pipeline is kept
+func (s *pipelineStore) addMergeRequestPipeline(key mergeRequestKey, l pipelineLink) {
+	s.mergeRequests.update(key, func(state mergeRequestState, _ bool) (mergeRequestState, bool) {
+		state.pipelines = slices.DeleteFunc(slices.Clone(state.pipelines), func(existing pipelineLink) bool {
+			return existing.pipelineId == l.pipelineId
+		})
+		state.pipelines = append(state.pipelines, l)
+		if len(state.pipelines) > storeMaxMergeRequestPipelines {
+			state.pipelines = state.pipelines[len(state.pipelines)-storeMaxMergeRequestPipelines:]
+		}
+		return state, true
+	})
+}
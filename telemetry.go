@@ -0,0 +1,161 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/receiver"
+	"go.opentelemetry.io/collector/receiver/receiverhelper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+const (
+	obsreportTransport = "http"
+	obsreportFormat    = "gitlab"
+
+	meterName = "github.com/nw0rn/gitlabreceiver"
+
+	attributeEvent      = "event"
+	attributeStatusCode = "status_code"
+	attributeReason     = "reason"
+	attributeSignal     = "signal"
+
+	// Event of webhooks which were rejected before their handler was known
+	eventUnknown = "unknown"
+
+	validationReasonToken     = "token"
+	validationReasonRequest   = "request"
+	validationReasonSignature = "signature"
+	validationReasonBodySize  = "body_size"
+	filterReasonRef           = "ref"
+	filterReasonProject       = "project"
+)
+
+// receiverTelemetry reports the accepted and refused telemetry of the receiver (obsreport) as well as receiver specific metrics
+// through the telemetry settings of the component
+type receiverTelemetry struct {
+	obsrecv            *receiverhelper.ObsReport
+	webhooks           metric.Int64Counter
+	decodeFailures     metric.Int64Counter
+	validationFailures metric.Int64Counter
+	filteredEvents     metric.Int64Counter
+	exportDuration     metric.Float64Histogram
+}
+
+func newReceiverTelemetry(settings receiver.Settings) (*receiverTelemetry, error) {
+	obsrecv, err := receiverhelper.NewObsReport(receiverhelper.ObsReportSettings{
+		ReceiverID:             settings.ID,
+		Transport:              obsreportTransport,
+		ReceiverCreateSettings: settings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &receiverTelemetry{obsrecv: obsrecv}
+	// Like the obsreport metrics, the receiver metrics are only reported with the basic telemetry level or above
+	meter := settings.MeterProvider.Meter(meterName)
+	if settings.MetricsLevel < configtelemetry.LevelBasic {
+		meter = noop.NewMeterProvider().Meter(meterName)
+	}
+	t.webhooks, err = meter.Int64Counter("otelcol_receiver_gitlab_webhooks",
+		metric.WithDescription("Number of received webhooks by event type and status code of the response"),
+		metric.WithUnit("{webhooks}"))
+	if err != nil {
+		return nil, err
+	}
+	t.decodeFailures, err = meter.Int64Counter("otelcol_receiver_gitlab_decode_failures",
+		metric.WithDescription("Number of webhooks whose body couldn't be decoded"),
+		metric.WithUnit("{webhooks}"))
+	if err != nil {
+		return nil, err
+	}
+	t.validationFailures, err = meter.Int64Counter("otelcol_receiver_gitlab_validation_failures",
+		metric.WithDescription("Number of rejected webhooks by the reason of the rejection (token, request, body_size or signature)"),
+		metric.WithUnit("{webhooks}"))
+	if err != nil {
+		return nil, err
+	}
+	t.filteredEvents, err = meter.Int64Counter("otelcol_receiver_gitlab_filtered_events",
+		metric.WithDescription("Number of events which weren't exported because of the configured filters"),
+		metric.WithUnit("{events}"))
+	if err != nil {
+		return nil, err
+	}
+	t.exportDuration, err = meter.Float64Histogram("otelcol_receiver_gitlab_export_duration",
+		metric.WithDescription("Duration of passing the telemetry of an event to the next consumer (or the traces queue)"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *receiverTelemetry) recordWebhook(ctx context.Context, event string, statusCode int) {
+	t.webhooks.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(attributeEvent, event),
+		attribute.String(attributeStatusCode, strconv.Itoa(statusCode))))
+}
+
+func (t *receiverTelemetry) recordDecodeFailure(ctx context.Context, event string) {
+	t.decodeFailures.Add(ctx, 1, metric.WithAttributes(attribute.String(attributeEvent, event)))
+}
+
+func (t *receiverTelemetry) recordValidationFailure(ctx context.Context, reason string) {
+	t.validationFailures.Add(ctx, 1, metric.WithAttributes(attribute.String(attributeReason, reason)))
+}
+
+func (t *receiverTelemetry) recordFiltered(ctx context.Context, reason string) {
+	t.filteredEvents.Add(ctx, 1, metric.WithAttributes(attribute.String(attributeReason, reason)))
+}
+
+func (t *receiverTelemetry) recordExport(ctx context.Context, signal pipeline.Signal, start time.Time) {
+	t.exportDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.String(attributeSignal, signal.String())))
+}
+
+func (t *receiverTelemetry) consumeTraces(ctx context.Context, td ptrace.Traces, next func(context.Context, ptrace.Traces) error) error {
+	ctx = t.obsrecv.StartTracesOp(ctx)
+	start := time.Now()
+	err := next(ctx, td)
+	t.recordExport(ctx, pipeline.SignalTraces, start)
+	t.obsrecv.EndTracesOp(ctx, obsreportFormat, td.SpanCount(), err)
+	return err
+}
+
+func (t *receiverTelemetry) consumeMetrics(ctx context.Context, md pmetric.Metrics, next func(context.Context, pmetric.Metrics) error) error {
+	ctx = t.obsrecv.StartMetricsOp(ctx)
+	start := time.Now()
+	err := next(ctx, md)
+	t.recordExport(ctx, pipeline.SignalMetrics, start)
+	t.obsrecv.EndMetricsOp(ctx, obsreportFormat, md.DataPointCount(), err)
+	return err
+}
+
+func (t *receiverTelemetry) consumeLogs(ctx context.Context, ld plog.Logs, next func(context.Context, plog.Logs) error) error {
+	ctx = t.obsrecv.StartLogsOp(ctx)
+	start := time.Now()
+	err := next(ctx, ld)
+	t.recordExport(ctx, pipeline.SignalLogs, start)
+	t.obsrecv.EndLogsOp(ctx, obsreportFormat, ld.LogRecordCount(), err)
+	return err
+}
+
+// statusRecorder captures the status code of the response for the webhooks metric
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,121 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestGitlabReceiverTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	settings := receivertest.NewNopSettings()
+	settings.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	settings.MetricsLevel = configtelemetry.LevelBasic
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Traces.Refs = []string{"main"}
+	cfg.Projects.Exclude = []string{"group/excluded"}
+	cfg.SecretToken = "secret"
+	glRcvr := newTestReceiver(t, cfg, settings)
+	glRcvr.nextTracesConsumer = new(consumertest.TracesSink)
+
+	send := func(token string, body string) {
+		req := httptest.NewRequest(http.MethodPost, cfg.Traces.UrlPath, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gitlab-Event", "Pipeline Hook")
+		req.Header.Set("X-Gitlab-Token", token)
+		glRcvr.handleEvent(context.Background(), httptest.NewRecorder(), req, []pipeline.Signal{pipeline.SignalTraces})
+	}
+
+	send("secret", mustMarshal(t, newFinishedPipelineEvent()))
+	feature := newFinishedPipelineEvent()
+	feature.Pipeline.Ref = "feature"
+	send("secret", mustMarshal(t, feature))
+	excluded := newFinishedPipelineEvent()
+	excluded.Project.Path = "group/excluded"
+	send("secret", mustMarshal(t, excluded))
+	send("invalid", mustMarshal(t, newFinishedPipelineEvent()))
+	send("secret", "{invalid")
+	//Unauthenticated webhooks can't create arbitrary event attributes
+	req := httptest.NewRequest(http.MethodPost, cfg.Traces.UrlPath, strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gitlab-Event", "random-value-1")
+	glRcvr.handleEvent(context.Background(), httptest.NewRecorder(), req, []pipeline.Signal{pipeline.SignalTraces})
+
+	glRcvr.nextTracesConsumer = consumertest.NewErr(errors.New("pipeline is backed up"))
+	send("secret", mustMarshal(t, newFinishedPipelineEvent()))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	assert.Equal(t, int64(3), sumValue(t, rm, "otelcol_receiver_gitlab_webhooks", attribute.String(attributeStatusCode, "200")), "exported and filtered pipelines")
+	assert.Equal(t, int64(2), sumValue(t, rm, "otelcol_receiver_gitlab_webhooks", attribute.String(attributeStatusCode, "401")))
+	assert.Equal(t, int64(1), sumValue(t, rm, "otelcol_receiver_gitlab_webhooks", attribute.String(attributeStatusCode, "400")))
+	assert.Equal(t, int64(1), sumValue(t, rm, "otelcol_receiver_gitlab_webhooks", attribute.String(attributeStatusCode, "500")))
+	assert.Equal(t, int64(2), sumValue(t, rm, "otelcol_receiver_gitlab_validation_failures", attribute.String(attributeReason, validationReasonToken)))
+	assert.Equal(t, int64(1), sumValue(t, rm, "otelcol_receiver_gitlab_webhooks", attribute.String(attributeEvent, eventUnknown)), "unknown event headers aren't recorded")
+	assert.Equal(t, int64(1), sumValue(t, rm, "otelcol_receiver_gitlab_decode_failures", attribute.String(attributeEvent, "Pipeline Hook")))
+	assert.Equal(t, int64(1), sumValue(t, rm, "otelcol_receiver_gitlab_filtered_events", attribute.String(attributeReason, filterReasonRef)))
+	assert.Equal(t, int64(1), sumValue(t, rm, "otelcol_receiver_gitlab_filtered_events", attribute.String(attributeReason, filterReasonProject)))
+
+	traces, err := newFinishedPipelineEvent().newTrace()
+	require.NoError(t, err)
+	spans := int64(traces.SpanCount())
+	assert.Equal(t, spans, sumValue(t, rm, "otelcol_receiver_accepted_spans"))
+	assert.Equal(t, spans, sumValue(t, rm, "otelcol_receiver_refused_spans"))
+
+	duration := findMetric(t, rm, "otelcol_receiver_gitlab_export_duration").Data.(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(2), duration.DataPoints[0].Count)
+}
+
+func mustMarshal(t *testing.T, event any) string {
+	body, err := json.Marshal(event)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func findMetric(t *testing.T, rm metricdata.ResourceMetrics, name string) metricdata.Metrics {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	require.Failf(t, "metric not found", "metric %s", name)
+	return metricdata.Metrics{}
+}
+
+// Sum of all data points which carry the given attributes
+func sumValue(t *testing.T, rm metricdata.ResourceMetrics, name string, attrs ...attribute.KeyValue) int64 {
+	sum, ok := findMetric(t, rm, name).Data.(metricdata.Sum[int64])
+	require.True(t, ok, "metric %s is not an int sum", name)
+
+	var value int64
+	for _, dp := range sum.DataPoints {
+		matches := true
+		for _, attr := range attrs {
+			v, ok := dp.Attributes.Value(attr.Key)
+			matches = matches && ok && v == attr.Value
+		}
+		if matches {
+			value += dp.Value
+		}
+	}
+	return value
+}
@@ -0,0 +1,254 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTestReportsDelay = 30 * time.Second
+	testReportsQueueSize    = 1000
+)
+
+// Parallel jobs are named "<name> <index>/<total>", their test suite is named after the job without the index
+var parallelJobSuffix = regexp.MustCompile(` \d+/\d+$`)
+
+// testReports adds the test report of finished pipelines to their exported trace. Gitlab parses the test reports of the
+// jobs asynchronously, therefore the report is fetched from the Gitlab REST API in the background after a delay.
+// Test reports which are still pending at shutdown are dropped.
+type testReports struct {
+	cfg     TestReports
+	logger  *zap.Logger
+	api     *gitlabClient
+	export  func(ctx context.Context, glRes gitlabResource) error
+	pending chan pendingTestReport
+	now     func() time.Time
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type pendingTestReport struct {
+	pipeline *glPipelineEvent
+	readyAt  time.Time
+}
+
+func newTestReports(cfg TestReports, api *gitlabClient, export func(ctx context.Context, glRes gitlabResource) error, logger *zap.Logger) *testReports {
+	return &testReports{
+		cfg:     cfg,
+		logger:  logger,
+		api:     api,
+		export:  export,
+		pending: make(chan pendingTestReport, testReportsQueueSize),
+		now:     time.Now,
+	}
+}
+
+func (t *testReports) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case r := <-t.pending:
+				if !t.wait(ctx, r.readyAt) {
+					return
+				}
+				err := t.exportTestReport(ctx, r.pipeline)
+				if err != nil && ctx.Err() == nil {
+					t.logger.Error("Unable to export the test report of the pipeline", zap.String("Pipeline", r.pipeline.Pipeline.Url), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func (t *testReports) shutdown() {
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.wg.Wait()
+}
+
+// The export of the pipeline is never blocked by its test report
+func (t *testReports) add(p *glPipelineEvent) {
+	select {
+	case t.pending <- pendingTestReport{pipeline: p, readyAt: t.now().Add(t.cfg.Delay)}:
+	default:
+		t.logger.Warn("Too many pending test reports, dropping the test report of the pipeline", zap.String("Pipeline", p.Pipeline.Url))
+	}
+}
+
+func (t *testReports) wait(ctx context.Context, readyAt time.Time) bool {
+	timer := time.NewTimer(readyAt.Sub(t.now()))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Pipelines without test reports are skipped
+func (t *testReports) exportTestReport(ctx context.Context, p *glPipelineEvent) error {
+	report, err := t.api.testReport(ctx, strconv.Itoa(p.Project.Id), p.Pipeline.Id)
+	if err != nil {
+		return err
+	}
+	if len(report.TestSuites) == 0 {
+		return nil
+	}
+	return t.export(ctx, &glTestReport{pipeline: p, report: report})
+}
+
+// glTestReport is exported as part of the trace of its pipeline. Test suites are children of the span of their job,
+// test cases are children of their suite.
+type glTestReport struct {
+	pipeline *glPipelineEvent
+	report   apiTestReport
+}
+
+func (r *glTestReport) newTrace() (*ptrace.Traces, error) {
+	p := r.pipeline
+	traceId, rootSpanId, err := p.traceContext()
+	if err != nil {
+		return nil, err
+	}
+
+	trace := ptrace.NewTraces()
+	rs := trace.ResourceSpans().AppendEmpty()
+	setProjectResource(rs.Resource(), p.Project)
+	rs.Resource().Attributes().PutStr(conventionsAttributeSpanSource, fmt.Sprintf("%s-receiver", typeStr.String()))
+
+	jobs := p.finishedJobs()
+	for _, suite := range r.report.TestSuites {
+		// Suites without a finished job of the pipeline are children of the root span and start with the pipeline
+		jobId := 0
+		parentSpanId := rootSpanId
+		start := p.Pipeline.CreatedAt
+		if js, ok := suiteJob(suite, jobs); ok {
+			jobId = js.job.Id
+			start = js.job.StartedAt
+			parentSpanId, err = p.jobSpanId(jobId)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		startTime, err := parseGitlabTime(start)
+		if err != nil {
+			return nil, err
+		}
+		err = r.createSuiteSpans(rs, traceId, parentSpanId, jobId, suite, startTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &trace, nil
+}
+
+// Test reports only contain the durations of the test cases, therefore the test cases are placed one after another
+// starting with their suite. Suites of parallel jobs are merged by Gitlab, their test cases may exceed the job span.
+func (r *glTestReport) createSuiteSpans(rs ptrace.ResourceSpans, traceId [16]byte, parentSpanId [8]byte, jobId int, suite apiTestSuite, startTime pcommon.Timestamp) error {
+	p := r.pipeline
+	suiteSpanId, err := getTestSuiteSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.hashTime(), strconv.Itoa(jobId), suite.Name)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("Test suite: %s", suite.Name)
+	createSpan(rs, traceId, suiteSpanId, parentSpanId, name, startTime, addSeconds(startTime, suite.TotalTime), suite)
+
+	caseStart := startTime
+	for i, c := range suite.TestCases {
+		spanId, err := getTestCaseSpanId(p.Pipeline.Sha, strconv.Itoa(p.Pipeline.Id), p.hashTime(), strconv.Itoa(jobId), suite.Name, i)
+		if err != nil {
+			return err
+		}
+		caseEnd := addSeconds(caseStart, c.ExecutionTime)
+		createSpan(rs, traceId, spanId, suiteSpanId, fmt.Sprintf("Test case: %s", c.Name), caseStart, caseEnd, c)
+		caseStart = caseEnd
+	}
+	return nil
+}
+
+// The test report has no span of its own
+func (r *glTestReport) setAttributes(s ptrace.Span) {}
+
+// The job of the suite is determined by the build ids of the suite, or by the name of the job if the ids are unknown
+func suiteJob(suite apiTestSuite, jobs []jobSpan) (jobSpan, bool) {
+	for _, id := range suite.BuildIds {
+		i := slices.IndexFunc(jobs, func(js jobSpan) bool { return js.job.Id == id })
+		if i >= 0 {
+			return jobs[i], true
+		}
+	}
+	i := slices.IndexFunc(jobs, func(js jobSpan) bool {
+		return js.job.Name == suite.Name || parallelJobSuffix.ReplaceAllString(js.job.Name, "") == suite.Name
+	})
+	if i >= 0 {
+		return jobs[i], true
+	}
+	return jobSpan{}, false
+}
+
+func addSeconds(t pcommon.Timestamp, seconds float64) pcommon.Timestamp {
+	return pcommon.NewTimestampFromTime(t.AsTime().Add(time.Duration(seconds * float64(time.Second))))
+}
+
+func (s apiTestSuite) setAttributes(span ptrace.Span) {
+	attrs := span.Attributes()
+	attrs.EnsureCapacity(8)
+	attrs.PutStr(conventionsAttributeTestSuiteName, s.Name)
+	attrs.PutStr(conventionsAttributeTestSuiteDuration, strconv.FormatFloat(s.TotalTime, 'f', -1, 64))
+	attrs.PutStr(conventionsAttributeTestSuiteTotalCount, strconv.Itoa(s.TotalCount))
+	attrs.PutStr(conventionsAttributeTestSuiteSuccessCount, strconv.Itoa(s.SuccessCount))
+	attrs.PutStr(conventionsAttributeTestSuiteFailedCount, strconv.Itoa(s.FailedCount))
+	attrs.PutStr(conventionsAttributeTestSuiteSkippedCount, strconv.Itoa(s.SkippedCount))
+	attrs.PutStr(conventionsAttributeTestSuiteErrorCount, strconv.Itoa(s.ErrorCount))
+	if s.SuiteError != "" {
+		attrs.PutStr(conventionsAttributeTestSuiteError, s.SuiteError)
+	}
+
+	status := "success"
+	if s.FailedCount > 0 || s.ErrorCount > 0 || s.SuiteError != "" {
+		status = "failed"
+	}
+	setSpanStatus(span, status)
+}
+
+func (c apiTestCase) setAttributes(span ptrace.Span) {
+	attrs := span.Attributes()
+	attrs.EnsureCapacity(7)
+	attrs.PutStr(conventionsAttributeTestCaseName, c.Name)
+	attrs.PutStr(conventionsAttributeTestCaseClassname, c.Classname)
+	attrs.PutStr(conventionsAttributeTestCaseFile, c.File)
+	attrs.PutStr(conventionsAttributeTestCaseStatus, c.Status)
+	attrs.PutStr(conventionsAttributeTestCaseDuration, strconv.FormatFloat(c.ExecutionTime, 'f', -1, 64))
+	if c.SystemOutput != "" {
+		attrs.PutStr(conventionsAttributeTestCaseSystemOutput, c.SystemOutput)
+	}
+	if c.StackTrace != "" {
+		attrs.PutStr(conventionsAttributeTestCaseStackTrace, c.StackTrace)
+	}
+
+	// Errors (e.g. exceptions outside of assertions) fail the test case like failed assertions
+	status := c.Status
+	if status == "error" {
+		status = "failed"
+	}
+	setSpanStatus(span, status)
+	span.Status().SetMessage(c.Status)
+}
@@ -0,0 +1,120 @@
+package gitlabreceiver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.uber.org/zap"
+)
+
+func TestGlTestReportNewTrace(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	p := newFinishedPipelineEvent()
+	report := gitlab.testReports[1]
+	report.TestSuites = append(report.TestSuites,
+		apiTestSuite{Name: "build", TotalTime: 1, TotalCount: 1, SuccessCount: 1, TestCases: []apiTestCase{{Status: "success", Name: "TestBuild", ExecutionTime: 1}}},
+		apiTestSuite{Name: "lint", TotalTime: 1, TotalCount: 1, ErrorCount: 1, TestCases: []apiTestCase{{Status: "error", Name: "TestLint", ExecutionTime: 1}}},
+	)
+
+	traces, err := (&glTestReport{pipeline: p, report: report}).newTrace()
+	require.NoError(t, err)
+	spans := collectSpans(*traces)
+	require.Len(t, spans, 8)
+
+	traceId, rootSpanId, err := p.traceContext()
+	require.NoError(t, err)
+	testJobSpanId, err := p.jobSpanId(11)
+	require.NoError(t, err)
+	buildJobSpanId, err := p.jobSpanId(12)
+	require.NoError(t, err)
+
+	//Suites are children of their job span, by build id or by name
+	suite := spans["Test suite: test"]
+	assert.Equal(t, traceId, [16]byte(suite.TraceID()))
+	assert.Equal(t, testJobSpanId, [8]byte(suite.ParentSpanID()))
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 30, 15, 0, time.UTC), suite.StartTimestamp().AsTime())
+	assert.Equal(t, 4500*time.Millisecond, suite.EndTimestamp().AsTime().Sub(suite.StartTimestamp().AsTime()))
+	assert.Equal(t, ptrace.StatusCodeError, suite.Status().Code())
+	assert.Equal(t, buildJobSpanId, [8]byte(spans["Test suite: build"].ParentSpanID()))
+	assert.Equal(t, rootSpanId, [8]byte(spans["Test suite: lint"].ParentSpanID()), "suites without job are children of the root span")
+
+	//Test cases are placed one after another
+	add, sub, mul := spans["Test case: TestAdd"], spans["Test case: TestSub"], spans["Test case: TestMul"]
+	assert.Equal(t, suite.SpanID(), add.ParentSpanID())
+	assert.Equal(t, suite.StartTimestamp(), add.StartTimestamp())
+	assert.Equal(t, add.EndTimestamp(), sub.StartTimestamp())
+	assert.Equal(t, sub.EndTimestamp(), mul.StartTimestamp())
+	assert.Equal(t, suite.EndTimestamp(), mul.EndTimestamp())
+	assert.Equal(t, ptrace.StatusCodeOk, add.Status().Code())
+	assert.Equal(t, ptrace.StatusCodeError, sub.Status().Code())
+	output, _ := sub.Attributes().Get(conventionsAttributeTestCaseSystemOutput)
+	assert.Equal(t, "expected 1, got 2", output.Str())
+	classname, _ := sub.Attributes().Get(conventionsAttributeTestCaseClassname)
+	assert.Equal(t, "calc", classname.Str())
+	assert.Equal(t, ptrace.StatusCodeError, spans["Test case: TestLint"].Status().Code())
+	assert.Equal(t, "error", spans["Test case: TestLint"].Status().Message())
+
+	//The span ids are deterministic, so that a redelivered pipeline doesn't duplicate the test spans
+	again, err := (&glTestReport{pipeline: p, report: report}).newTrace()
+	require.NoError(t, err)
+	assert.Equal(t, sub.SpanID(), collectSpans(*again)["Test case: TestSub"].SpanID())
+}
+
+func TestSuiteJob(t *testing.T) {
+	jobs := []jobSpan{
+		{job: Job{Id: 1, Name: "rspec 1/2"}},
+		{job: Job{Id: 2, Name: "rspec 2/2"}},
+		{job: Job{Id: 3, Name: "lint"}},
+	}
+	tests := []struct {
+		name     string
+		suite    apiTestSuite
+		expected int
+	}{
+		{name: "build id", suite: apiTestSuite{Name: "rspec", BuildIds: []int{2, 1}}, expected: 2},
+		{name: "unknown build id", suite: apiTestSuite{Name: "lint", BuildIds: []int{4}}, expected: 3},
+		{name: "job name", suite: apiTestSuite{Name: "lint"}, expected: 3},
+		{name: "parallel job name", suite: apiTestSuite{Name: "rspec"}, expected: 1},
+		{name: "unknown job", suite: apiTestSuite{Name: "unknown"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			js, ok := suiteJob(tc.suite, jobs)
+			assert.Equal(t, tc.expected != 0, ok)
+			assert.Equal(t, tc.expected, js.job.Id)
+		})
+	}
+}
+
+func TestTestReports(t *testing.T) {
+	gitlab := newFakeGitlab(t)
+	sink := new(consumertest.TracesSink)
+	glRcvr := newTestReceiver(t, createDefaultConfig().(*Config), receivertest.NewNopSettings())
+	glRcvr.nextTracesConsumer = sink
+	glRcvr.testReports = newTestReports(TestReports{Enabled: true}, newTestGitlabClient(t, gitlab), glRcvr.exportTraces, zap.NewNop())
+	glRcvr.testReports.start()
+	defer glRcvr.testReports.shutdown()
+
+	//The test report is exported after the pipeline trace
+	require.NoError(t, glRcvr.handlePipelineTraces(context.Background(), newFinishedPipelineEvent()))
+	require.Eventually(t, func() bool { return len(sink.AllTraces()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	spans := collectSpans(sink.AllTraces()[1])
+	assert.Contains(t, spans, "Test suite: test")
+	assert.Equal(t, collectSpans(sink.AllTraces()[0])["Job: test - 11 - Stage: test"].SpanID(), spans["Test suite: test"].ParentSpanID())
+
+	//Pipelines without test report are skipped
+	p := newFinishedPipelineEvent()
+	p.Pipeline.Id = 2
+	require.NoError(t, glRcvr.testReports.exportTestReport(context.Background(), p))
+	assert.Len(t, sink.AllTraces(), 2)
+
+	p.Project.Id = 43
+	assert.ErrorContains(t, glRcvr.testReports.exportTestReport(context.Background(), p), "failed with status 404")
+}
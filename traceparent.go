@@ -0,0 +1,32 @@
+package gitlabreceiver
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// TraceParent returns the W3C traceparent header value (https://www.w3.org/TR/trace-context/#traceparent-header) of the
+// span of a Gitlab CI job. Spans which are created within the job with this traceparent as parent become children of the
+// job span of the pipeline trace. The arguments are available within the job as CI_COMMIT_SHA, CI_PIPELINE_ID and CI_JOB_ID.
+//
+// The traceparent only matches the exported trace if the receiver is configured with traces.stable_trace_id, otherwise
+// the trace id depends on the finished time of the pipeline, which isn't known while the job is running.
+func TraceParent(commitSHA string, pipelineId int, jobId int) (string, error) {
+	if pipelineId <= 0 || jobId <= 0 {
+		return "", errors.New("pipelineId and jobId must be greater than 0")
+	}
+
+	traceId, err := getTraceId(commitSHA, strconv.Itoa(pipelineId), stableTraceIdTime)
+	if err != nil {
+		return "", err
+	}
+	spanId, err := getJobSpanId(commitSHA, strconv.Itoa(pipelineId), stableTraceIdTime, strconv.Itoa(jobId))
+	if err != nil {
+		return "", err
+	}
+
+	// Version 00, the job span is always sampled
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(traceId[:]), hex.EncodeToString(spanId[:])), nil
+}
@@ -0,0 +1,46 @@
+package gitlabreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceParent(t *testing.T) {
+	p := newFinishedPipelineEvent()
+	p.stableTraceId = true
+	traces, err := p.newTrace()
+	require.NoError(t, err)
+	job := collectSpans(*traces)["Job: test - 11 - Stage: test"]
+
+	traceParent, err := TraceParent("abc123", 1, 11)
+	require.NoError(t, err)
+	assert.Equal(t, "00-"+job.TraceID().String()+"-"+job.SpanID().String()+"-01", traceParent)
+
+	//The trace context doesn't depend on the finished time of the pipeline
+	p.Pipeline.FinishedAt = "2024-01-01 13:40:15 UTC"
+	retried, err := p.newTrace()
+	require.NoError(t, err)
+	assert.Equal(t, job.TraceID(), collectSpans(*retried)["Job: test - 11 - Stage: test"].TraceID())
+}
+
+func TestTraceParentInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		sha        string
+		pipelineId int
+		jobId      int
+	}{
+		{name: "missing sha", pipelineId: 1, jobId: 11},
+		{name: "missing pipeline id", sha: "abc123", jobId: 11},
+		{name: "missing job id", sha: "abc123", pipelineId: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := TraceParent(tc.sha, tc.pipelineId, tc.jobId)
+			assert.Error(t, err)
+		})
+	}
+}
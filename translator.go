@@ -1,16 +1,21 @@
 package gitlabreceiver
 
 import (
-	crand "crypto/rand"
 	"crypto/sha256"
-	"encoding/binary"
 	"errors"
-	"math/rand"
+	"fmt"
+	"strconv"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
+// Replaces the finished time of the pipeline in the hash if stable trace ids are configured
+const stableTraceIdTime = "stable"
+
+// Replaces the commit sha in the hash of merge request traces
+const mergeRequestHashPrefix = "merge_request:"
+
 // We use the first 16 bytes from the generated hash
 // Details: https://www.w3.org/TR/trace-context/#traceparent-header-field-values
 func getTraceId(commitSHA string, pipelineId string, endTime string) ([16]byte, error) {
@@ -48,19 +53,73 @@ func generateHash(commitSHA string, pipelineId string, endTime string) ([32]byte
 	return sha256.Sum256([]byte(commitSHA + pipelineId + endTime)), nil
 }
 
-func getRandomSpanId() pcommon.SpanID {
-	var rngSeed int64
-	_ = binary.Read(crand.Reader, binary.LittleEndian, &rngSeed)
-	randSource := rand.New(rand.NewSource(rngSeed))
+// Child spans of the pipeline (stages, jobs and deployments) use the same hash as the root span id, extended by the
+// kind and id of the child span. Redelivered webhooks therefore create the same span ids and retried pipelines, which
+// are exported as new traces because of their new finished time, create new span ids.
+// The end time is checked before it is extended, the extended end time is never empty.
+func getChildSpanId(commitSHA string, pipelineId string, endTime string, child string) ([8]byte, error) {
+	var spanId [8]byte
+
+	if commitSHA == "" || pipelineId == "" || endTime == "" {
+		return spanId, fmt.Errorf("commitSHA, pipelineId, and endTime of the child span %q must be non-empty", child)
+	}
+	hash, err := generateHash(commitSHA, pipelineId, endTime+child)
+	if err != nil {
+		return spanId, err
+	}
+	copy(spanId[:], hash[16:24])
+
+	return spanId, nil
+}
+
+// The job id is unique for every attempt of a job
+func getJobSpanId(commitSHA string, pipelineId string, endTime string, jobId string) ([8]byte, error) {
+	return getChildSpanId(commitSHA, pipelineId, endTime, "job:"+jobId)
+}
+
+func getStageSpanId(commitSHA string, pipelineId string, endTime string, stage string) ([8]byte, error) {
+	return getChildSpanId(commitSHA, pipelineId, endTime, "stage:"+stage)
+}
+
+func getDeploymentSpanId(commitSHA string, pipelineId string, endTime string, deploymentId string) ([8]byte, error) {
+	return getChildSpanId(commitSHA, pipelineId, endTime, "deployment:"+deploymentId)
+}
+
+// The queued and execution span of a job are derived from the span id of the job, so that they can be calculated
+// wherever the job span is created (pipeline trace and job events)
+func getJobPhaseSpanId(jobSpanId [8]byte, phase string) [8]byte {
+	var spanId [8]byte
+	hash := sha256.Sum256(append(jobSpanId[:], []byte(phase)...))
+	copy(spanId[:], hash[:8])
+	return spanId
+}
 
-	var sid [8]byte
-	randSource.Read(sid[:])
-	spanID := pcommon.SpanID(sid)
+// Test suites are named after their job, the same suite can be part of the report of several jobs (e.g. parallel jobs)
+func getTestSuiteSpanId(commitSHA string, pipelineId string, endTime string, jobId string, suite string) ([8]byte, error) {
+	return getChildSpanId(commitSHA, pipelineId, endTime, "suite:"+jobId+":"+suite)
+}
 
-	return spanID
+// Test cases aren't unique by their name, therefore the position within the suite is part of the span id
+func getTestCaseSpanId(commitSHA string, pipelineId string, endTime string, jobId string, suite string, index int) ([8]byte, error) {
+	return getChildSpanId(commitSHA, pipelineId, endTime, "test:"+jobId+":"+suite+":"+strconv.Itoa(index))
 }
 
-func createSpan(rs ptrace.ResourceSpans, traceId [16]byte, spanId [8]byte, parentSpanId [8]byte, name string, startTime pcommon.Timestamp, endTime pcommon.Timestamp, glRes gitlabResource) {
+// Merge requests are identified by their iid within their project. The trace of a merge request doesn't depend on any
+// time, so that all lifecycle events of the merge request and its pipelines can refer to the same trace.
+func getMergeRequestTraceId(projectId string, iid string) ([16]byte, error) {
+	return getTraceId(mergeRequestHashPrefix, projectId, "!"+iid)
+}
+
+func getMergeRequestRootSpanId(projectId string, iid string) ([8]byte, error) {
+	return getRootSpanId(mergeRequestHashPrefix, projectId, "!"+iid)
+}
+
+// Redelivered lifecycle events create the same span id
+func getMergeRequestEventSpanId(projectId string, iid string, action string, eventTime string) ([8]byte, error) {
+	return getChildSpanId(mergeRequestHashPrefix, projectId, "!"+iid, "event:"+action+":"+eventTime)
+}
+
+func createSpan(rs ptrace.ResourceSpans, traceId [16]byte, spanId [8]byte, parentSpanId [8]byte, name string, startTime pcommon.Timestamp, endTime pcommon.Timestamp, glRes spanResource) ptrace.Span {
 	scopeSpanSlice := rs.ScopeSpans()
 	scopeSpanSlice.EnsureCapacity(1)
 	ss := scopeSpanSlice.AppendEmpty()
@@ -77,4 +136,6 @@ func createSpan(rs ptrace.ResourceSpans, traceId [16]byte, spanId [8]byte, paren
 
 	span.SetName(name)
 	glRes.setAttributes(span)
+
+	return span
 }
@@ -7,12 +7,37 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestgetRandomSpanId(t *testing.T) {
-	spanId1 := getRandomSpanId()
-	assert.Len(t, spanId1, 8, "span ID should be 8 bytes long")
+func TestGetChildSpanIds(t *testing.T) {
+	jobSpanId, err := getJobSpanId("abc123", "1", "10", "11")
+	assert.NoError(t, err)
+	assert.Equal(t, generateExpectedSpanId("abc123", "1", "10job:11"), jobSpanId)
 
-	spanId2 := getRandomSpanId()
-	assert.NotEqual(t, spanId1, spanId2, "span IDs should be unique")
+	//Span ids are deterministic
+	sameJobSpanId, err := getJobSpanId("abc123", "1", "10", "11")
+	assert.NoError(t, err)
+	assert.Equal(t, jobSpanId, sameJobSpanId)
+
+	otherJobSpanId, err := getJobSpanId("abc123", "1", "10", "12")
+	assert.NoError(t, err)
+	assert.NotEqual(t, jobSpanId, otherJobSpanId)
+
+	//A retried pipeline has a new finished time and therefore new span ids
+	retriedJobSpanId, err := getJobSpanId("abc123", "1", "20", "11")
+	assert.NoError(t, err)
+	assert.NotEqual(t, jobSpanId, retriedJobSpanId)
+
+	stageSpanId, err := getStageSpanId("abc123", "1", "10", "11")
+	assert.NoError(t, err)
+	assert.NotEqual(t, jobSpanId, stageSpanId, "span ids of different kinds of child spans must not collide")
+
+	rootSpanId, err := getRootSpanId("abc123", "1", "10")
+	assert.NoError(t, err)
+	assert.NotEqual(t, rootSpanId, jobSpanId)
+
+	_, err = getJobSpanId("abc123", "1", "", "11")
+	assert.ErrorContains(t, err, `child span "job:11"`)
+	_, err = getStageSpanId("", "1", "10", "test")
+	assert.ErrorContains(t, err, `child span "stage:test"`)
 }
 
 func TestGetTraceAndSpanIds(t *testing.T) {